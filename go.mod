@@ -1,3 +1,3 @@
 module utils_generics
 
-go 1.21.3
+go 1.23
@@ -0,0 +1,198 @@
+// Package parity is a test suite run against both utils and utils_generics,
+// so the interface{} API behaves the same whichever package it comes from.
+package parity
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// API is the interface{} API both packages have.
+type API struct {
+	OrChannel                         func(channels ...<-chan interface{}) <-chan interface{}
+	RepeatValueChannel                func(done <-chan interface{}, values ...interface{}) <-chan interface{}
+	RepeatFnChannel                   func(done <-chan interface{}, fn func() interface{}) <-chan interface{}
+	TakeChannel                       func(done <-chan interface{}, valueStream <-chan interface{}, num int) <-chan interface{}
+	OrDoneChannel                     func(done <-chan interface{}, c <-chan interface{}) <-chan interface{}
+	FanInChannel                      func(done <-chan interface{}, channels ...<-chan interface{}) <-chan interface{}
+	TeeChannel                        func(done <-chan interface{}, in <-chan interface{}) (<-chan interface{}, <-chan interface{})
+	BridgeChannel                     func(done <-chan interface{}, chanStream <-chan <-chan interface{}) <-chan interface{}
+	GeneratorToChannel                func(done <-chan interface{}, slice ...interface{}) <-chan interface{}
+	GeneratorFromStringArrayToChannel func(done <-chan interface{}, slice []string) <-chan interface{}
+	BufferChannel                     func(done <-chan interface{}, in <-chan interface{}, limit int) <-chan interface{}
+}
+
+// Run runs the suite against api.
+func Run(t *testing.T, api API) {
+	t.Run("Or", func(t *testing.T) { testOr(t, api) })
+	t.Run("RepeatAndTake", func(t *testing.T) { testRepeatAndTake(t, api) })
+	t.Run("TakeClosed", func(t *testing.T) { testTakeClosed(t, api) })
+	t.Run("OrDone", func(t *testing.T) { testOrDone(t, api) })
+	t.Run("FanIn", func(t *testing.T) { testFanIn(t, api) })
+	t.Run("Tee", func(t *testing.T) { testTee(t, api) })
+	t.Run("Bridge", func(t *testing.T) { testBridge(t, api) })
+	t.Run("Generators", func(t *testing.T) { testGenerators(t, api) })
+	t.Run("Buffer", func(t *testing.T) { testBuffer(t, api) })
+	t.Run("Done", func(t *testing.T) { testDone(t, api) })
+}
+
+// collect reads c until it is closed, failing if that takes too long.
+func collect(t *testing.T, c <-chan interface{}) []interface{} {
+	t.Helper()
+	var values []interface{}
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case v, ok := <-c:
+			if ok == false {
+				return values
+			}
+			values = append(values, v)
+		case <-timeout:
+			t.Fatalf("expected the channel to close, got %v so far", values)
+		}
+	}
+}
+
+func expect(t *testing.T, expected string, values []interface{}) {
+	t.Helper()
+	if got := fmt.Sprint(values); got != expected {
+		t.Fatalf("expected %v, \n got %v", expected, got)
+	}
+}
+
+func testOr(t *testing.T, api API) {
+	if api.OrChannel() != nil {
+		t.Fatalf("expected nil for no channels")
+	}
+	for _, n := range []int{1, 2, 3, 5} {
+		channels := make([]<-chan interface{}, n)
+		var last chan interface{}
+		for i := range channels {
+			last = make(chan interface{})
+			channels[i] = last
+		}
+		close(last)
+		collect(t, api.OrChannel(channels...))
+	}
+}
+
+func testRepeatAndTake(t *testing.T, api API) {
+	done := make(chan interface{})
+	defer close(done)
+
+	expect(t, "[1 2 1 2 1]", collect(t, api.TakeChannel(done, api.RepeatValueChannel(done, 1, 2), 5)))
+
+	i := 0
+	count := func() interface{} { i++; return i }
+	expect(t, "[1 2 3]", collect(t, api.TakeChannel(done, api.RepeatFnChannel(done, count), 3)))
+}
+
+func testTakeClosed(t *testing.T, api API) {
+	done := make(chan interface{})
+	defer close(done)
+
+	// the stream closes before num values, so Take closes too.
+	expect(t, "[1 2]", collect(t, api.TakeChannel(done, api.GeneratorToChannel(done, 1, 2), 5)))
+}
+
+func testOrDone(t *testing.T, api API) {
+	done := make(chan interface{})
+	defer close(done)
+
+	expect(t, "[a b]", collect(t, api.OrDoneChannel(done, api.GeneratorToChannel(done, "a", "b"))))
+}
+
+func testFanIn(t *testing.T, api API) {
+	done := make(chan interface{})
+	defer close(done)
+
+	values := collect(t, api.FanInChannel(done,
+		api.GeneratorToChannel(done, 1, 2),
+		api.GeneratorToChannel(done, 3),
+		api.GeneratorToChannel(done)))
+	sum := 0
+	for _, v := range values {
+		sum += v.(int)
+	}
+	if len(values) != 3 || sum != 6 {
+		t.Fatalf("expected 1, 2 and 3 in any order, got %v", values)
+	}
+}
+
+func testTee(t *testing.T, api API) {
+	done := make(chan interface{})
+	defer close(done)
+
+	out1, out2 := api.TeeChannel(done, api.GeneratorToChannel(done, 1, 2, 3))
+	var values1, values2 []interface{}
+	for v := range out1 {
+		values1 = append(values1, v)
+		values2 = append(values2, <-out2)
+	}
+	expect(t, "[1 2 3]", values1)
+	expect(t, "[1 2 3]", values2)
+	collect(t, out2)
+}
+
+func testBridge(t *testing.T, api API) {
+	done := make(chan interface{})
+	defer close(done)
+
+	chanStream := make(chan (<-chan interface{}))
+	go func() {
+		defer close(chanStream)
+		for i := 0; i < 3; i++ {
+			chanStream <- api.GeneratorToChannel(done, i, i*10)
+		}
+	}()
+	expect(t, "[0 0 1 10 2 20]", collect(t, api.BridgeChannel(done, chanStream)))
+}
+
+func testGenerators(t *testing.T, api API) {
+	done := make(chan interface{})
+	defer close(done)
+
+	expect(t, "[1 two 3]", collect(t, api.GeneratorToChannel(done, 1, "two", 3.0)))
+	expect(t, "[tom dick harry]", collect(t, api.GeneratorFromStringArrayToChannel(done, []string{"tom", "dick", "harry"})))
+	expect(t, "[]", collect(t, api.GeneratorToChannel(done)))
+}
+
+func testBuffer(t *testing.T, api API) {
+	done := make(chan interface{})
+	defer close(done)
+
+	buffered := api.BufferChannel(done, api.GeneratorToChannel(done, 1, 2, 3, 4), 2)
+	if cap(buffered) != 2 {
+		t.Fatalf("expected a buffer of 2, got %d", cap(buffered))
+	}
+	expect(t, "[1 2 3 4]", collect(t, buffered))
+}
+
+// testDone checks every stage closes its channel once done is closed.
+func testDone(t *testing.T, api API) {
+	done := make(chan interface{})
+	ones := api.RepeatValueChannel(done, 1)
+	fn := api.RepeatFnChannel(done, func() interface{} { return 1 })
+	take := api.TakeChannel(done, api.RepeatValueChannel(done, 1), 100)
+	orDone := api.OrDoneChannel(done, api.RepeatValueChannel(done, 1))
+	fanIn := api.FanInChannel(done, api.RepeatValueChannel(done, 1), api.RepeatValueChannel(done, 2))
+	out1, out2 := api.TeeChannel(done, api.RepeatValueChannel(done, 1))
+	buffered := api.BufferChannel(done, api.RepeatValueChannel(done, 1), 3)
+	chanStream := make(chan (<-chan interface{}))
+	go func() {
+		select {
+		case <-done:
+		case chanStream <- api.RepeatValueChannel(done, 1):
+		}
+	}()
+	bridged := api.BridgeChannel(done, chanStream)
+
+	<-take
+	<-bridged
+	close(done)
+	for _, c := range []<-chan interface{}{ones, fn, take, orDone, fanIn, out1, out2, buffered, bridged} {
+		collect(t, c)
+	}
+}
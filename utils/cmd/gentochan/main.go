@@ -0,0 +1,193 @@
+// Gentochan writes the ToXChannel functions, which take an interface{}
+// channel and convert it to a channel of one type, for the utils package or
+// for your own types.
+//
+// Usage:
+//
+//	gentochan [-package name] [-types list] [-imports list] [-o file]
+//
+// The types are separated by commas, each one a type optionally followed by
+// =Name for the name of its function, ToNameChannel.  Without a name the type
+// is capitalised, so time.Duration gives ToDurationChannel and *Order gives
+// ToOrderChannel.  The default types are the ones the utils package has always
+// had.
+//
+// Outside the utils package the functions use utils.OrDoneChannel, so for
+// your own types add a line like this to your package and run go generate:
+//
+//	//go:generate go run utils_generics/utils/cmd/gentochan -package orders -types Order,*Order=OrderPtr -o toChannel_gen.go
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// utilsPath is the import path of the utils package.
+const utilsPath = "utils_generics/utils"
+
+// builtinTypes are the converters in the utils package.
+const builtinTypes = "byte,int8,int16,int32,int,int64,uint8=UInt8,uint16=UInt16,uint32=UInt32,uint=UInt,uint64=UInt64," +
+	"string,bool,rune,float32,float64,complex64,complex128,uintptr=UIntPtr"
+
+// config is what to generate.
+type config struct {
+	Package string
+	Types   string
+	Imports string
+}
+
+// converter is one ToXChannel function.
+type converter struct {
+	Type    string
+	Name    string
+	Article string // a or an, to go before the type in the comment
+}
+
+var tmpl = template.Must(template.New("tochan").Parse(`// Code generated by gentochan. DO NOT EDIT.
+
+package {{.Package}}
+
+{{if .Imports}}import (
+{{range .Imports}}	"{{.}}"
+{{end}})
+{{end}}
+// For type safety, you may want to convert an interface{} channel to a native type.
+// To add your own struct/type run gentochan, see utils/cmd/gentochan.
+{{range .Converters}}
+// To{{.Name}}Channel takes an interface channel and converts it to {{.Article}} {{.Type}} channel.
+func To{{.Name}}Channel(done <-chan interface{}, valueStream <-chan interface{}) <-chan {{.Type}} {
+	typedStream := make(chan {{.Type}})
+	go func() {
+		defer close(typedStream)
+		for v := range {{$.Qualifier}}OrDoneChannel(done, valueStream) {
+			select {
+			case <-done:
+				return
+			case typedStream <- v.({{.Type}}):
+			}
+		}
+	}()
+	return typedStream
+}
+{{end}}`))
+
+// parseTypes parses the -types list.
+func parseTypes(list string) ([]converter, error) {
+	var converters []converter
+	seen := make(map[string]string)
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		typ, name, named := strings.Cut(field, "=")
+		typ, name = strings.TrimSpace(typ), strings.TrimSpace(name)
+		if named == false {
+			name = typeName(typ)
+		}
+		base := strings.TrimLeft(typ, "*[]")
+		if base == "" || isIdentifier(name) == false {
+			return nil, fmt.Errorf("gentochan: bad type %q, want type or type=Name", field)
+		}
+		if other, ok := seen[name]; ok {
+			return nil, fmt.Errorf("gentochan: %s and %s both make To%sChannel", other, typ, name)
+		}
+		seen[name] = typ
+
+		article := "a"
+		if strings.ContainsRune("aeioAEIO", rune(base[0])) {
+			article = "an"
+		}
+		converters = append(converters, converter{Type: typ, Name: name, Article: article})
+	}
+	if len(converters) == 0 {
+		return nil, fmt.Errorf("gentochan: no types")
+	}
+	return converters, nil
+}
+
+// typeName is the name of the function for typ without a =Name:
+// the type without its package, pointer or slice, capitalised.
+func typeName(typ string) string {
+	name := strings.TrimLeft(typ, "*[]")
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	if name == "" {
+		return ""
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func isIdentifier(name string) bool {
+	for i, r := range name {
+		if r != '_' && unicode.IsLetter(r) == false && (i == 0 || unicode.IsDigit(r) == false) {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// generate returns the gofmt'ed source.
+func generate(c config) ([]byte, error) {
+	converters, err := parseTypes(c.Types)
+	if err != nil {
+		return nil, err
+	}
+	var imports []string
+	for _, path := range strings.Split(c.Imports, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			imports = append(imports, path)
+		}
+	}
+	qualifier := ""
+	if c.Package != "utils" {
+		qualifier = "utils."
+		imports = append(imports, utilsPath)
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, struct {
+		Package    string
+		Imports    []string
+		Qualifier  string
+		Converters []converter
+	}{c.Package, imports, qualifier, converters})
+	if err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("gentochan: %v", err)
+	}
+	return src, nil
+}
+
+func main() {
+	var c config
+	output := flag.String("o", "", "write to `file` rather than standard output")
+	flag.StringVar(&c.Package, "package", "utils", "the `name` of the package to generate")
+	flag.StringVar(&c.Types, "types", builtinTypes, "the `list` of types, type or type=Name separated by commas")
+	flag.StringVar(&c.Imports, "imports", "", "the `list` of import paths the types need, separated by commas")
+	flag.Parse()
+
+	src, err := generate(c)
+	if err == nil {
+		if *output == "" {
+			_, err = os.Stdout.Write(src)
+		} else {
+			err = os.WriteFile(*output, src, 0644)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestGeneratedUpToDate fails when toChannelUtils_gen.go has been edited by
+// hand, or the template has changed without running go generate.
+func TestGeneratedUpToDate(t *testing.T) {
+	expected, err := generate(config{Package: "utils", Types: builtinTypes})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	got, err := os.ReadFile("../../toChannelUtils_gen.go")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !bytes.Equal(expected, got) {
+		t.Fatalf("toChannelUtils_gen.go is out of date, run go generate in utils")
+	}
+}
+
+func TestGenerateUserTypes(t *testing.T) {
+	src, err := generate(config{Package: "orders", Types: "Order, *Order=OrderPtr, time.Duration", Imports: "time"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := []string{
+		"package orders",
+		`"utils_generics/utils"`,
+		`"time"`,
+		"// ToOrderChannel takes an interface channel and converts it to an Order channel.",
+		"func ToOrderPtrChannel(done <-chan interface{}, valueStream <-chan interface{}) <-chan *Order {",
+		"func ToDurationChannel(done <-chan interface{}, valueStream <-chan interface{}) <-chan time.Duration {",
+		"range utils.OrDoneChannel(done, valueStream)",
+	}
+	for _, e := range expected {
+		if !strings.Contains(string(src), e) {
+			t.Fatalf("expected %s in\n%s", e, src)
+		}
+	}
+}
+
+func TestGenerateBadTypes(t *testing.T) {
+	for _, types := range []string{"", "int=", "int=2x", "*", "int,int"} {
+		if _, err := generate(config{Package: "utils", Types: types}); err == nil {
+			t.Fatalf("expected an error for %q", types)
+		}
+	}
+}
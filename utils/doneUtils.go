@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"context"
+
+	"utils_generics/utils_generics"
+)
+
+// Every utility takes its done channel as a <-chan interface{}.  These turn a
+// context, or a done channel of any other type such as a <-chan struct{},
+// into one.  Call cancel once done is no longer needed, as with
+// context.WithCancel, it closes done and lets go of whatever was waiting.
+//
+//	done, cancel := utils.DoneFromContext(ctx)
+//	defer cancel()
+//	values := utils.TakeChannel(done, utils.RepeatValueChannel(done, 1), 10)
+
+// DoneFromContext returns a done channel that closes once ctx is done.
+func DoneFromContext(ctx context.Context) (done <-chan interface{}, cancel func()) {
+	return utils_generics.DoneFromContext(ctx)
+}
+
+// DoneFrom returns a done channel that closes once signal, a done channel of
+// any type, is closed or sends.
+func DoneFrom[D any](signal <-chan D) (done <-chan interface{}, cancel func()) {
+	return utils_generics.DoneFrom(signal)
+}
@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDoneFromContextAndChannel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ctxDone, stopCtx := DoneFromContext(ctx)
+	defer stopCtx()
+	stop := make(chan struct{})
+	stopDone, stopStop := DoneFrom(stop)
+	defer stopStop()
+	done := OrChannel(ctxDone, stopDone)
+
+	take := TakeChannel
+	repeat := RepeatValueChannel
+	values := take(done, repeat(done, 1), 1000000)
+	<-values
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("expected done to close")
+	}
+	for range values {
+	}
+
+	close(stop)
+	select {
+	case <-stopDone:
+	case <-time.After(time.Second):
+		t.Fatalf("expected done to close")
+	}
+}
@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"testing"
+
+	"utils_generics/internal/parity"
+)
+
+func TestParity(t *testing.T) {
+	parity.Run(t, parity.API{
+		OrChannel:                         OrChannel,
+		RepeatValueChannel:                RepeatValueChannel,
+		RepeatFnChannel:                   RepeatFnChannel,
+		TakeChannel:                       TakeChannel,
+		OrDoneChannel:                     OrDoneChannel,
+		FanInChannel:                      FanInChannel,
+		TeeChannel:                        TeeChannel,
+		BridgeChannel:                     BridgeChannel,
+		GeneratorToChannel:                GeneratorToChannel,
+		GeneratorFromStringArrayToChannel: GeneratorFromStringArrayToChannel,
+		BufferChannel:                     BufferChannel,
+	})
+}
@@ -0,0 +1,310 @@
+// Code generated by gentochan. DO NOT EDIT.
+
+package utils
+
+// For type safety, you may want to convert an interface{} channel to a native type.
+// To add your own struct/type run gentochan, see utils/cmd/gentochan.
+
+// ToByteChannel takes an interface channel and converts it to a byte channel.
+func ToByteChannel(done <-chan interface{}, valueStream <-chan interface{}) <-chan byte {
+	typedStream := make(chan byte)
+	go func() {
+		defer close(typedStream)
+		for v := range OrDoneChannel(done, valueStream) {
+			select {
+			case <-done:
+				return
+			case typedStream <- v.(byte):
+			}
+		}
+	}()
+	return typedStream
+}
+
+// ToInt8Channel takes an interface channel and converts it to an int8 channel.
+func ToInt8Channel(done <-chan interface{}, valueStream <-chan interface{}) <-chan int8 {
+	typedStream := make(chan int8)
+	go func() {
+		defer close(typedStream)
+		for v := range OrDoneChannel(done, valueStream) {
+			select {
+			case <-done:
+				return
+			case typedStream <- v.(int8):
+			}
+		}
+	}()
+	return typedStream
+}
+
+// ToInt16Channel takes an interface channel and converts it to an int16 channel.
+func ToInt16Channel(done <-chan interface{}, valueStream <-chan interface{}) <-chan int16 {
+	typedStream := make(chan int16)
+	go func() {
+		defer close(typedStream)
+		for v := range OrDoneChannel(done, valueStream) {
+			select {
+			case <-done:
+				return
+			case typedStream <- v.(int16):
+			}
+		}
+	}()
+	return typedStream
+}
+
+// ToInt32Channel takes an interface channel and converts it to an int32 channel.
+func ToInt32Channel(done <-chan interface{}, valueStream <-chan interface{}) <-chan int32 {
+	typedStream := make(chan int32)
+	go func() {
+		defer close(typedStream)
+		for v := range OrDoneChannel(done, valueStream) {
+			select {
+			case <-done:
+				return
+			case typedStream <- v.(int32):
+			}
+		}
+	}()
+	return typedStream
+}
+
+// ToIntChannel takes an interface channel and converts it to an int channel.
+func ToIntChannel(done <-chan interface{}, valueStream <-chan interface{}) <-chan int {
+	typedStream := make(chan int)
+	go func() {
+		defer close(typedStream)
+		for v := range OrDoneChannel(done, valueStream) {
+			select {
+			case <-done:
+				return
+			case typedStream <- v.(int):
+			}
+		}
+	}()
+	return typedStream
+}
+
+// ToInt64Channel takes an interface channel and converts it to an int64 channel.
+func ToInt64Channel(done <-chan interface{}, valueStream <-chan interface{}) <-chan int64 {
+	typedStream := make(chan int64)
+	go func() {
+		defer close(typedStream)
+		for v := range OrDoneChannel(done, valueStream) {
+			select {
+			case <-done:
+				return
+			case typedStream <- v.(int64):
+			}
+		}
+	}()
+	return typedStream
+}
+
+// ToUInt8Channel takes an interface channel and converts it to a uint8 channel.
+func ToUInt8Channel(done <-chan interface{}, valueStream <-chan interface{}) <-chan uint8 {
+	typedStream := make(chan uint8)
+	go func() {
+		defer close(typedStream)
+		for v := range OrDoneChannel(done, valueStream) {
+			select {
+			case <-done:
+				return
+			case typedStream <- v.(uint8):
+			}
+		}
+	}()
+	return typedStream
+}
+
+// ToUInt16Channel takes an interface channel and converts it to a uint16 channel.
+func ToUInt16Channel(done <-chan interface{}, valueStream <-chan interface{}) <-chan uint16 {
+	typedStream := make(chan uint16)
+	go func() {
+		defer close(typedStream)
+		for v := range OrDoneChannel(done, valueStream) {
+			select {
+			case <-done:
+				return
+			case typedStream <- v.(uint16):
+			}
+		}
+	}()
+	return typedStream
+}
+
+// ToUInt32Channel takes an interface channel and converts it to a uint32 channel.
+func ToUInt32Channel(done <-chan interface{}, valueStream <-chan interface{}) <-chan uint32 {
+	typedStream := make(chan uint32)
+	go func() {
+		defer close(typedStream)
+		for v := range OrDoneChannel(done, valueStream) {
+			select {
+			case <-done:
+				return
+			case typedStream <- v.(uint32):
+			}
+		}
+	}()
+	return typedStream
+}
+
+// ToUIntChannel takes an interface channel and converts it to a uint channel.
+func ToUIntChannel(done <-chan interface{}, valueStream <-chan interface{}) <-chan uint {
+	typedStream := make(chan uint)
+	go func() {
+		defer close(typedStream)
+		for v := range OrDoneChannel(done, valueStream) {
+			select {
+			case <-done:
+				return
+			case typedStream <- v.(uint):
+			}
+		}
+	}()
+	return typedStream
+}
+
+// ToUInt64Channel takes an interface channel and converts it to a uint64 channel.
+func ToUInt64Channel(done <-chan interface{}, valueStream <-chan interface{}) <-chan uint64 {
+	typedStream := make(chan uint64)
+	go func() {
+		defer close(typedStream)
+		for v := range OrDoneChannel(done, valueStream) {
+			select {
+			case <-done:
+				return
+			case typedStream <- v.(uint64):
+			}
+		}
+	}()
+	return typedStream
+}
+
+// ToStringChannel takes an interface channel and converts it to a string channel.
+func ToStringChannel(done <-chan interface{}, valueStream <-chan interface{}) <-chan string {
+	typedStream := make(chan string)
+	go func() {
+		defer close(typedStream)
+		for v := range OrDoneChannel(done, valueStream) {
+			select {
+			case <-done:
+				return
+			case typedStream <- v.(string):
+			}
+		}
+	}()
+	return typedStream
+}
+
+// ToBoolChannel takes an interface channel and converts it to a bool channel.
+func ToBoolChannel(done <-chan interface{}, valueStream <-chan interface{}) <-chan bool {
+	typedStream := make(chan bool)
+	go func() {
+		defer close(typedStream)
+		for v := range OrDoneChannel(done, valueStream) {
+			select {
+			case <-done:
+				return
+			case typedStream <- v.(bool):
+			}
+		}
+	}()
+	return typedStream
+}
+
+// ToRuneChannel takes an interface channel and converts it to a rune channel.
+func ToRuneChannel(done <-chan interface{}, valueStream <-chan interface{}) <-chan rune {
+	typedStream := make(chan rune)
+	go func() {
+		defer close(typedStream)
+		for v := range OrDoneChannel(done, valueStream) {
+			select {
+			case <-done:
+				return
+			case typedStream <- v.(rune):
+			}
+		}
+	}()
+	return typedStream
+}
+
+// ToFloat32Channel takes an interface channel and converts it to a float32 channel.
+func ToFloat32Channel(done <-chan interface{}, valueStream <-chan interface{}) <-chan float32 {
+	typedStream := make(chan float32)
+	go func() {
+		defer close(typedStream)
+		for v := range OrDoneChannel(done, valueStream) {
+			select {
+			case <-done:
+				return
+			case typedStream <- v.(float32):
+			}
+		}
+	}()
+	return typedStream
+}
+
+// ToFloat64Channel takes an interface channel and converts it to a float64 channel.
+func ToFloat64Channel(done <-chan interface{}, valueStream <-chan interface{}) <-chan float64 {
+	typedStream := make(chan float64)
+	go func() {
+		defer close(typedStream)
+		for v := range OrDoneChannel(done, valueStream) {
+			select {
+			case <-done:
+				return
+			case typedStream <- v.(float64):
+			}
+		}
+	}()
+	return typedStream
+}
+
+// ToComplex64Channel takes an interface channel and converts it to a complex64 channel.
+func ToComplex64Channel(done <-chan interface{}, valueStream <-chan interface{}) <-chan complex64 {
+	typedStream := make(chan complex64)
+	go func() {
+		defer close(typedStream)
+		for v := range OrDoneChannel(done, valueStream) {
+			select {
+			case <-done:
+				return
+			case typedStream <- v.(complex64):
+			}
+		}
+	}()
+	return typedStream
+}
+
+// ToComplex128Channel takes an interface channel and converts it to a complex128 channel.
+func ToComplex128Channel(done <-chan interface{}, valueStream <-chan interface{}) <-chan complex128 {
+	typedStream := make(chan complex128)
+	go func() {
+		defer close(typedStream)
+		for v := range OrDoneChannel(done, valueStream) {
+			select {
+			case <-done:
+				return
+			case typedStream <- v.(complex128):
+			}
+		}
+	}()
+	return typedStream
+}
+
+// ToUIntPtrChannel takes an interface channel and converts it to a uintptr channel.
+func ToUIntPtrChannel(done <-chan interface{}, valueStream <-chan interface{}) <-chan uintptr {
+	typedStream := make(chan uintptr)
+	go func() {
+		defer close(typedStream)
+		for v := range OrDoneChannel(done, valueStream) {
+			select {
+			case <-done:
+				return
+			case typedStream <- v.(uintptr):
+			}
+		}
+	}()
+	return typedStream
+}
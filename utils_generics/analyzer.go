@@ -0,0 +1,174 @@
+package utils_generics
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Finding the stage that limits a running Pipeline created WithMetrics.
+//
+// Over the sampling interval each stage is scored by how much of the time the
+// stage after it waited on it (blocked on receive), how little of the time it
+// waited on the stage after it (blocked on send), and how much of the time
+// the stage before it was waiting for it to take its output.  A slow stage
+// scores high on all three, everything before it waits to send and
+// everything after it waits to receive.
+//
+// If the stages were added with Inputs those are the stages before each one,
+// otherwise they are taken to be a chain, in the order they were added.
+
+// StageReport is the analysis of one stage over the sampling interval.
+type StageReport struct {
+	Name             string
+	Throughput       float64 // items per second taken by the next stage
+	ReceiveBlocked   float64 // fraction of the interval waiting on the stage
+	SendBlocked      float64 // fraction of the interval waiting on the next stage
+	BufferFill       float64 // most items seen in the output buffer / its capacity
+	Score            float64 // how likely this stage is to be the bottleneck, 0 to 1
+	SuggestedWorkers float64 // multiply the workers in this stage by this, 0 for no change
+	SuggestedBuffer  int     // output buffer size to try, 0 for no change
+}
+
+// BottleneckReport is the result of analysing a Pipeline.
+type BottleneckReport struct {
+	Pipeline string
+	Interval time.Duration
+	Stages   []StageReport
+	Limiting string // the stage limiting the pipeline, "" if there isn't one
+}
+
+// limitingScore is the lowest score for a stage to be called the bottleneck.
+const limitingScore = 0.25
+
+// AnalyzeBottleneck samples the Pipeline's metrics for interval and reports
+// on the stage limiting it. It blocks for interval.
+func AnalyzeBottleneck(p *Pipeline, interval time.Duration) BottleneckReport {
+	before := p.Snapshot()
+	time.Sleep(interval)
+	return AnalyzeSnapshots(before, p.Snapshot())
+}
+
+// AnalyzeSnapshots reports on the stage limiting a Pipeline between two of its snapshots.
+func AnalyzeSnapshots(before PipelineMetrics, after PipelineMetrics) BottleneckReport {
+	report := BottleneckReport{
+		Pipeline: after.Pipeline,
+		Interval: after.Time.Sub(before.Time),
+		Stages:   make([]StageReport, len(after.Stages)),
+	}
+	seconds := report.Interval.Seconds()
+	if seconds <= 0 || len(before.Stages) != len(after.Stages) {
+		return report
+	}
+
+	fraction := func(d time.Duration) float64 {
+		return math.Min(1, d.Seconds()/seconds)
+	}
+	// blocked returns the fraction of the interval output port of stage i
+	// spent blocked, as picked out by wait.
+	blocked := func(i int, port int, wait func(om *OutputMetrics) time.Duration) float64 {
+		b, a := &before.Stages[i], &after.Stages[i]
+		if port >= len(b.Outputs) || port >= len(a.Outputs) {
+			return 0
+		}
+		return fraction(wait(&a.Outputs[port]) - wait(&b.Outputs[port]))
+	}
+	receiving := func(om *OutputMetrics) time.Duration { return om.BlockedReceive }
+	sending := func(om *OutputMetrics) time.Duration { return om.BlockedSend }
+	for i := range after.Stages {
+		b, a := &before.Stages[i], &after.Stages[i]
+		sr := &report.Stages[i]
+		sr.Name = a.Name
+		sr.Throughput = float64(a.ItemsOut-b.ItemsOut) / seconds
+		// each output waits on its own, a stage is as blocked as its most blocked output.
+		for port := range a.Outputs {
+			sr.ReceiveBlocked = math.Max(sr.ReceiveBlocked, blocked(i, port, receiving))
+			sr.SendBlocked = math.Max(sr.SendBlocked, blocked(i, port, sending))
+		}
+		if a.BufferCap > 0 {
+			sr.BufferFill = float64(a.MaxBufferLen) / float64(a.BufferCap)
+		}
+	}
+
+	index := make(map[string]int, len(after.Stages))
+	topology := false
+	for i := range after.Stages {
+		index[after.Stages[i].Name] = i
+		topology = topology || len(after.Stages[i].Inputs) > 0
+	}
+	// upstream returns how much of the time each of the outputs feeding
+	// stage i was waiting for it to take an item.
+	upstream := func(i int) []float64 {
+		if topology == false {
+			if i == 0 {
+				return nil
+			}
+			return []float64{report.Stages[i-1].SendBlocked}
+		}
+		var inputs []float64
+		for j, name := range after.Stages[i].Inputs {
+			inputs = append(inputs, blocked(index[name], after.Stages[i].InputPorts[j], sending))
+		}
+		return inputs
+	}
+
+	best := 0.0
+	for i := range report.Stages {
+		sr := &report.Stages[i]
+		inputs := upstream(i)
+		upstreamWaiting := 1.0
+		if len(inputs) > 0 {
+			upstreamWaiting = 0
+			for _, waiting := range inputs {
+				upstreamWaiting = math.Max(upstreamWaiting, waiting)
+			}
+		}
+		sr.Score = sr.ReceiveBlocked * (1 - sr.SendBlocked) * upstreamWaiting
+
+		switch {
+		case sr.Score >= limitingScore:
+			// the stage before could go 1/(1-waiting) times faster if this one kept up.
+			sr.SuggestedWorkers = 2
+			if len(inputs) > 0 {
+				sr.SuggestedWorkers = math.Ceil(1 / math.Max(0.05, 1-upstreamWaiting))
+			}
+		case sr.BufferFill >= 1 && sr.ReceiveBlocked > 0.1 && sr.SendBlocked > 0.1:
+			// bursty, full at times and empty at others, more room would smooth it out.
+			sr.SuggestedBuffer = 2 * after.Stages[i].BufferCap
+		}
+
+		if sr.Score >= limitingScore && sr.Score > best {
+			best = sr.Score
+			report.Limiting = sr.Name
+		}
+	}
+	return report
+}
+
+// String formats the report as a table, for printing.
+func (r BottleneckReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "pipeline %q over %v\n", r.Pipeline, r.Interval.Round(time.Millisecond))
+	tw := tabwriter.NewWriter(&sb, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "stage\titems/s\trecv blocked\tsend blocked\tbuffer fill\tscore\tsuggestion")
+	for _, sr := range r.Stages {
+		suggestion := ""
+		switch {
+		case sr.SuggestedWorkers > 0:
+			suggestion = fmt.Sprintf("x%.0f workers", sr.SuggestedWorkers)
+		case sr.SuggestedBuffer > 0:
+			suggestion = fmt.Sprintf("buffer %d", sr.SuggestedBuffer)
+		}
+		fmt.Fprintf(tw, "%s\t%.1f\t%.0f%%\t%.0f%%\t%.0f%%\t%.2f\t%s\n",
+			sr.Name, sr.Throughput, 100*sr.ReceiveBlocked, 100*sr.SendBlocked, 100*sr.BufferFill, sr.Score, suggestion)
+	}
+	tw.Flush()
+	if r.Limiting == "" {
+		sb.WriteString("no limiting stage found\n")
+	} else {
+		fmt.Fprintf(&sb, "limiting stage: %s\n", r.Limiting)
+	}
+	return sb.String()
+}
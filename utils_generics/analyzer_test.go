@@ -0,0 +1,112 @@
+package utils_generics
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestAnalyzeBottleneck(t *testing.T) {
+	p := NewPipeline("bottleneck", WithMetrics())
+
+	// a worker which just takes time to run.
+	sleeper := func(done <-chan interface{}, valueStream <-chan interface{}) <-chan interface{} {
+		out := make(chan interface{})
+		go func() {
+			defer close(out)
+			for val := range OrDoneChannel(done, valueStream) {
+				time.Sleep(2 * time.Millisecond)
+				select {
+				case <-done:
+					return
+				case out <- val:
+				}
+			}
+		}()
+		return out
+	}
+
+	ones := AddStage(p, "repeat", func(done <-chan interface{}) <-chan interface{} {
+		return RepeatValueChannel(done, 1)
+	})
+	buffered := AddStage(p, "buffer", func(done <-chan interface{}) <-chan interface{} {
+		return BufferChannel(done, ones, 4)
+	}, Inputs(ones))
+	workers := AddStage(p, "workers", func(done <-chan interface{}) <-chan interface{} {
+		return FanInChannel(done, sleeper(done, buffered), sleeper(done, buffered))
+	}, Inputs(buffered))
+	p.Start()
+	defer func() {
+		p.Stop()
+		p.Wait()
+	}()
+	go Drain(p.Done(), workers)
+
+	time.Sleep(50 * time.Millisecond) // let it get going
+	report := AnalyzeBottleneck(p, 300*time.Millisecond)
+	fmt.Print(report)
+
+	if report.Limiting != "workers" {
+		t.Fatalf("expected workers to be the limiting stage, got %q", report.Limiting)
+	}
+	if report.Stages[2].SuggestedWorkers < 2 {
+		t.Fatalf("expected a suggestion to add workers, got %v", report.Stages[2].SuggestedWorkers)
+	}
+}
+
+func TestAnalyzeSnapshotsWithoutMetrics(t *testing.T) {
+	p := NewPipeline("no metrics")
+	AddStage(p, "repeat", func(done <-chan interface{}) <-chan interface{} {
+		return RepeatValueChannel(done, 1)
+	})
+	before := p.Snapshot()
+	after := p.Snapshot()
+	after.Time = before.Time.Add(time.Second)
+
+	report := AnalyzeSnapshots(before, after)
+	if report.Limiting != "" {
+		t.Fatalf("expected no limiting stage without metrics, got %q", report.Limiting)
+	}
+}
+
+func TestAnalyzeBottleneckStuck(t *testing.T) {
+	p := NewPipeline("stuck", WithMetrics())
+	ones := AddStage(p, "repeat", func(done <-chan interface{}) <-chan interface{} {
+		return RepeatValueChannel(done, 1)
+	})
+	// a stage that takes one item and then never reads its input again.
+	stuck := AddStage(p, "stuck", func(done <-chan interface{}) <-chan interface{} {
+		out := make(chan interface{})
+		go func() {
+			defer close(out)
+			select {
+			case <-done:
+				return
+			case v := <-ones:
+				select {
+				case <-done:
+				case out <- v:
+				}
+			}
+			<-done
+		}()
+		return out
+	}, Inputs(ones))
+	p.Start()
+	defer func() {
+		p.Stop()
+		p.Wait()
+	}()
+	go Drain(p.Done(), stuck)
+
+	time.Sleep(20 * time.Millisecond) // let it get stuck
+	report := AnalyzeBottleneck(p, 200*time.Millisecond)
+	fmt.Print(report)
+
+	if report.Limiting != "stuck" {
+		t.Fatalf("expected stuck to be the limiting stage, got %q", report.Limiting)
+	}
+	if report.Stages[0].SendBlocked < 0.9 || report.Stages[1].ReceiveBlocked < 0.9 {
+		t.Fatalf("expected repeat blocked on send and stuck blocked on receive, got %+v", report.Stages)
+	}
+}
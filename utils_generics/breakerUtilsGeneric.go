@@ -0,0 +1,250 @@
+package utils_generics
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// A circuit breaker stops a stage from hammering a dependency that is down.
+//
+// While the breaker is closed every item is passed to fn.  Once too many of
+// the recent calls have failed the breaker opens, and items are passed
+// straight to the fallback, or the dead-letter channel, without calling fn.
+// After the cool-down the breaker is half-open and lets a few trial calls
+// through: if they all succeed it closes again, if any fails it opens again.
+//
+//	breaker := NewCircuitBreaker(BreakerOptions{FailureRate: 0.5, CoolDown: 10 * time.Second})
+//	prices, failed := CircuitBreakerChannel(done, orders, "price", breaker, lookupPrice, cachedPrice)
+//
+// One CircuitBreaker can be shared by several stages calling the same dependency.
+
+// ErrBreakerOpen is the error given to the fallback, or put in the dead
+// letter, for an item that was not tried because the breaker was open.
+var ErrBreakerOpen = errors.New("utils_generics: circuit breaker open")
+
+// BreakerState is the state of a CircuitBreaker.
+type BreakerState int
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerOpen
+	BreakerHalfOpen
+)
+
+func (state BreakerState) String() string {
+	switch state {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("BreakerState(%d)", int(state))
+}
+
+// BreakerChange is sent on the Changes channel when the breaker changes state.
+type BreakerChange struct {
+	From BreakerState
+	To   BreakerState
+	Time time.Time
+}
+
+// BreakerOptions configures a CircuitBreaker.
+type BreakerOptions struct {
+	// Window is how many of the most recent calls the failure rate is taken over, 20 if 0.
+	Window int
+	// MinCalls is how many calls there must be in the window before the
+	// breaker can open, Window if 0.
+	MinCalls int
+	// FailureRate is the fraction of the calls in the window that must fail
+	// for the breaker to open, 0.5 if 0.
+	FailureRate float64
+	// CoolDown is how long the breaker stays open, 30s if 0.
+	CoolDown time.Duration
+	// HalfOpenCalls is how many trial calls must succeed to close it again, 1 if 0.
+	HalfOpenCalls int
+}
+
+// CircuitBreaker tracks the calls to one dependency, see above.
+type CircuitBreaker struct {
+	opts    BreakerOptions
+	changes chan BreakerChange
+
+	mu       sync.Mutex
+	state    BreakerState
+	gen      uint64 // changes with every state, so late results are ignored
+	window   []bool // true for a failed call
+	next     int
+	calls    int
+	failures int
+	openedAt time.Time
+	trials   int // trial calls let through while half-open
+	passed   int // trial calls that succeeded
+}
+
+// NewCircuitBreaker returns a closed CircuitBreaker.
+func NewCircuitBreaker(opts BreakerOptions) *CircuitBreaker {
+	if opts.Window <= 0 {
+		opts.Window = 20
+	}
+	if opts.MinCalls <= 0 || opts.MinCalls > opts.Window {
+		opts.MinCalls = opts.Window
+	}
+	if opts.FailureRate <= 0 {
+		opts.FailureRate = 0.5
+	}
+	if opts.CoolDown <= 0 {
+		opts.CoolDown = 30 * time.Second
+	}
+	if opts.HalfOpenCalls <= 0 {
+		opts.HalfOpenCalls = 1
+	}
+	return &CircuitBreaker{
+		opts:    opts,
+		changes: make(chan BreakerChange, 16),
+		window:  make([]bool, opts.Window),
+	}
+}
+
+// State returns the state of the breaker now.
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.coolDown(time.Now())
+	return b.state
+}
+
+// Changes returns a channel of the breaker's state changes.  It is buffered,
+// changes are dropped rather than hold up the stage if nobody reads them.
+func (b *CircuitBreaker) Changes() <-chan BreakerChange {
+	return b.changes
+}
+
+// allow returns true if a call may be made, and the generation to record its result with.
+func (b *CircuitBreaker) allow() (uint64, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.coolDown(time.Now())
+	switch b.state {
+	case BreakerOpen:
+		return b.gen, false
+	case BreakerHalfOpen:
+		if b.trials >= b.opts.HalfOpenCalls {
+			return b.gen, false
+		}
+		b.trials++
+	}
+	return b.gen, true
+}
+
+// record records the result of a call allowed in generation gen.
+func (b *CircuitBreaker) record(gen uint64, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if gen != b.gen {
+		return
+	}
+	now := time.Now()
+
+	if b.state == BreakerHalfOpen {
+		if err != nil {
+			b.open(now)
+			return
+		}
+		b.passed++
+		if b.passed >= b.opts.HalfOpenCalls {
+			b.setState(BreakerClosed, now)
+		}
+		return
+	}
+
+	if b.calls == len(b.window) {
+		if b.window[b.next] {
+			b.failures--
+		}
+	} else {
+		b.calls++
+	}
+	b.window[b.next] = err != nil
+	if err != nil {
+		b.failures++
+	}
+	b.next = (b.next + 1) % len(b.window)
+
+	if b.calls >= b.opts.MinCalls && float64(b.failures) >= b.opts.FailureRate*float64(b.calls) {
+		b.open(now)
+	}
+}
+
+func (b *CircuitBreaker) open(now time.Time) {
+	b.openedAt = now
+	b.setState(BreakerOpen, now)
+}
+
+// coolDown moves an open breaker to half-open once the cool-down is over.
+func (b *CircuitBreaker) coolDown(now time.Time) {
+	if b.state == BreakerOpen && now.Sub(b.openedAt) >= b.opts.CoolDown {
+		b.setState(BreakerHalfOpen, now)
+	}
+}
+
+// setState changes state, starting the window and trials afresh.
+func (b *CircuitBreaker) setState(state BreakerState, now time.Time) {
+	change := BreakerChange{From: b.state, To: state, Time: now}
+	b.state = state
+	b.gen++
+	b.next, b.calls, b.failures = 0, 0, 0
+	b.trials, b.passed = 0, 0
+	select {
+	case b.changes <- change:
+	default:
+	}
+}
+
+// CircuitBreakerChannel sends fn(v) for every value in the stream, in order,
+// while the breaker lets it.  When fn fails, or the breaker is open, the item
+// and the error are given to fallback instead, which may be nil.  Items the
+// fallback fails too, or that have no fallback, go to the dead-letter channel,
+// with stage as their Stage.
+//
+// The dead-letter channel is closed along with the values channel.  It must
+// be read, the stage waits for each dead letter to be taken before carrying on.
+func CircuitBreakerChannel[T any, U any, D any](done <-chan D, valueStream <-chan T, stage string, breaker *CircuitBreaker, fn func(T) (U, error), fallback func(T, error) (U, error)) (<-chan U, <-chan DeadLetter) {
+	breakerStream := make(chan U)
+	deadLetters := make(chan DeadLetter)
+	go func() {
+		defer close(deadLetters)
+		defer close(breakerStream)
+		for v := range OrDoneTChannel(done, valueStream) {
+			var u U
+			err := ErrBreakerOpen
+			attempts := 0
+			if gen, ok := breaker.allow(); ok {
+				u, err = fn(v)
+				attempts = 1
+				breaker.record(gen, err)
+			}
+			if err != nil && fallback != nil {
+				u, err = fallback(v, err)
+			}
+
+			if err != nil {
+				select {
+				case <-done:
+					return
+				case deadLetters <- newDeadLetter(stage, v, err, attempts):
+				}
+				continue
+			}
+			select {
+			case <-done:
+				return
+			case breakerStream <- u:
+			}
+		}
+	}()
+	return breakerStream, deadLetters
+}
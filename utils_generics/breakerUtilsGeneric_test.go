@@ -0,0 +1,119 @@
+package utils_generics
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerChannel(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	errDown := errors.New("down")
+	down := true
+	calls := 0
+	fn := func(v int) (int, error) {
+		calls++
+		if down {
+			return 0, errDown
+		}
+		return v, nil
+	}
+	fallback := func(v int, err error) (int, error) {
+		if err == ErrBreakerOpen {
+			return -v, nil
+		}
+		return 0, err
+	}
+
+	breaker := NewCircuitBreaker(BreakerOptions{Window: 4, CoolDown: 20 * time.Millisecond})
+	in := make(chan int)
+	values, deadLetters := CircuitBreakerChannel(done, in, "breaker", breaker, fn, fallback)
+	// send v and return what came out, or the dead letter's error.
+	send := func(v int) (int, error) {
+		in <- v
+		select {
+		case u := <-values:
+			return u, nil
+		case letter := <-deadLetters:
+			if letter.Stage != "breaker" {
+				t.Fatalf("expected a dead letter from breaker, got %+v", letter)
+			}
+			return 0, letter.Err
+		}
+	}
+
+	// the first 4 calls fail and open the breaker.
+	for i := 1; i <= 4; i++ {
+		if _, err := send(i); err != errDown {
+			t.Fatalf("expected %v, got %v", errDown, err)
+		}
+	}
+	if change := <-breaker.Changes(); change.From != BreakerClosed || change.To != BreakerOpen {
+		t.Fatalf("expected closed to open, got %+v", change)
+	}
+
+	// while open, fn is not called and the fallback is used.
+	if u, err := send(5); u != -5 || err != nil || calls != 4 {
+		t.Fatalf("expected the fallback's -5 without a call, got %d, %v after %d calls", u, err, calls)
+	}
+
+	// after the cool-down one trial call closes it again.
+	time.Sleep(30 * time.Millisecond)
+	down = false
+	if u, err := send(6); u != 6 || err != nil {
+		t.Fatalf("expected 6, got %d, %v", u, err)
+	}
+	if breaker.State() != BreakerClosed {
+		t.Fatalf("expected closed, got %v", breaker.State())
+	}
+	expected := []BreakerChange{{From: BreakerOpen, To: BreakerHalfOpen}, {From: BreakerHalfOpen, To: BreakerClosed}}
+	for _, e := range expected {
+		if change := <-breaker.Changes(); change.From != e.From || change.To != e.To {
+			t.Fatalf("expected %v to %v, got %+v", e.From, e.To, change)
+		}
+	}
+}
+
+func TestCircuitBreakerHalfOpenFails(t *testing.T) {
+	breaker := NewCircuitBreaker(BreakerOptions{Window: 2, CoolDown: 10 * time.Millisecond})
+	errDown := errors.New("down")
+	for i := 0; i < 2; i++ {
+		gen, _ := breaker.allow()
+		breaker.record(gen, errDown)
+	}
+	if breaker.State() != BreakerOpen {
+		t.Fatalf("expected open, got %v", breaker.State())
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	gen, ok := breaker.allow()
+	if ok == false {
+		t.Fatalf("expected a trial call to be allowed")
+	}
+	if _, ok := breaker.allow(); ok {
+		t.Fatalf("expected only one trial call")
+	}
+	breaker.record(gen, errDown)
+	if breaker.State() != BreakerOpen {
+		t.Fatalf("expected a failed trial to open it again, got %v", breaker.State())
+	}
+}
+
+func TestCircuitBreakerWindow(t *testing.T) {
+	breaker := NewCircuitBreaker(BreakerOptions{Window: 4, FailureRate: 0.75})
+	errDown := errors.New("down")
+	// 2 failures in every 4 calls never reaches 75%.
+	for i := 0; i < 20; i++ {
+		gen, _ := breaker.allow()
+		if i%2 == 0 {
+			breaker.record(gen, errDown)
+		} else {
+			breaker.record(gen, nil)
+		}
+	}
+	if breaker.State() != BreakerClosed {
+		t.Fatalf("expected closed, got %v", breaker.State())
+	}
+}
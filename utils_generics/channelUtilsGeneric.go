@@ -23,6 +23,11 @@ import (
 //
 //     <-or ( doneChannel1, doneChannel2,.... )
 func OrChannel(channels ... <-chan interface{}) <-chan interface{} {
+	return OrTChannel(channels...)
+}
+
+// OrTChannel is OrChannel for channels of any type.
+func OrTChannel[T any](channels ...<-chan T) <-chan T {
 	switch len(channels) {
 	case 0:
 		return nil
@@ -30,22 +35,22 @@ func OrChannel(channels ... <-chan interface{}) <-chan interface{} {
 		return channels[0]
 	}
 
-	orDone := make(chan interface{})
+	orDone := make(chan T)
 	go func() {
 		defer close(orDone)
 
 		switch len(channels) {
 		case 2:
 			select {
-				case <- channels[0]:
-				case <- channels[1]:
+			case <-channels[0]:
+			case <-channels[1]:
 			}
 		default:
 			select {
-				case <- channels[0]:
-				case <- channels[1]:
-				case <- channels[2]:
-				case <- OrChannel(append(channels[3:], orDone)...):
+			case <-channels[0]:
+			case <-channels[1]:
+			case <-channels[2]:
+			case <-OrTChannel(append(channels[3:], orDone)...):
 			}
 		}
 	}()
@@ -55,7 +60,12 @@ func OrChannel(channels ... <-chan interface{}) <-chan interface{} {
 // RepeatChannel will repeat the values you pass to it infinitely until you tell it to stop.
 // pp. 109
 func RepeatValueChannel(done <- chan interface{}, values ...interface{}) <-chan interface{} {
-	valStream := make(chan interface{})
+	return RepeatValueTChannel(done, values...)
+}
+
+// RepeatValueTChannel is RepeatValueChannel for values of any type.
+func RepeatValueTChannel[T any, D any](done <-chan D, values ...T) <-chan T {
+	valStream := make(chan T)
 	go func() {
 		defer close(valStream)
 		for {
@@ -74,7 +84,12 @@ func RepeatValueChannel(done <- chan interface{}, values ...interface{}) <-chan
 // RepeatFuncChannel will call the func you pass to it infinitely until you tell it to stop.
 // pp. 109
 func RepeatFnChannel(done <- chan interface{}, fn func() interface{}) <-chan interface{} {
-	valStream := make(chan interface{})
+	return RepeatFnTChannel(done, fn)
+}
+
+// RepeatFnTChannel is RepeatFnChannel for functions returning any type.
+func RepeatFnTChannel[T any, D any](done <-chan D, fn func() T) <-chan T {
+	valStream := make(chan T)
 	go func() {
 		defer close(valStream)
 		for {
@@ -91,16 +106,30 @@ func RepeatFnChannel(done <- chan interface{}, fn func() interface{}) <-chan int
 // TakeChannel will only take the first num items from the incoming stream.
 // pp. 110
 func TakeChannel(done <- chan interface{}, valueStream <-chan interface{}, num int) <-chan interface{} {
-	takeStream := make(chan interface{})
+	return TakeTChannel(done, valueStream, num)
+}
+
+// TakeTChannel is TakeChannel for a stream of any type.
+// If the incoming stream closes before num items it closes its stream too.
+func TakeTChannel[T any, D any](done <-chan D, valueStream <-chan T, num int) <-chan T {
+	takeStream := make(chan T)
 	go func() {
 		defer close(takeStream)
-			for i := 0; i < num; i++ {
+		for i := 0; i < num; i++ {
+			select {
+			case <-done:
+				return
+			case v, ok := <-valueStream:
+				if ok == false {
+					return
+				}
 				select {
 				case <-done:
 					return
-				case takeStream <- <- valueStream:
+				case takeStream <- v:
 				}
 			}
+		}
 	}()
 	return takeStream
 }
@@ -110,7 +139,12 @@ func TakeChannel(done <- chan interface{}, valueStream <-chan interface{}, num i
 // or the channel passed in is closed.  Useful with a raw channel
 // pp.119-120
 func OrDoneChannel(done <-chan interface{}, c <-chan interface{}) <-chan interface{} {
-	valStream := make(chan interface{})
+	return OrDoneTChannel(done, c)
+}
+
+// OrDoneTChannel is OrDoneChannel for a stream of any type.
+func OrDoneTChannel[T any, D any](done <-chan D, c <-chan T) <-chan T {
+	valStream := make(chan T)
 	go func() {
 		defer close(valStream)
 		for {
@@ -127,7 +161,7 @@ func OrDoneChannel(done <-chan interface{}, c <-chan interface{}) <-chan interfa
 				}
 			}
 		}
-    }()
+	}()
 	return valStream
 }
 
@@ -136,24 +170,29 @@ func OrDoneChannel(done <-chan interface{}, c <-chan interface{}) <-chan interfa
 // to be passed along to the next channel.
 // pp. 117
 func FanInChannel(done <-chan interface{}, channels ... <-chan interface{}) <-chan interface{} {
-    var wg sync.WaitGroup
-    multiplexedStream := make(chan interface{})
-
-    multiplex := func(c <- chan interface{}) {
-    	defer wg.Done()
-    	for i := range c {
-    		select {
-    		case <- done:
-			return
-		case multiplexedStream <- i:
+	return FanInTChannel(done, channels...)
+}
+
+// FanInTChannel is FanInChannel for channels of any type.
+func FanInTChannel[T any, D any](done <-chan D, channels ...<-chan T) <-chan T {
+	var wg sync.WaitGroup
+	multiplexedStream := make(chan T)
+
+	multiplex := func(c <-chan T) {
+		defer wg.Done()
+		for i := range c {
+			select {
+			case <-done:
+				return
+			case multiplexedStream <- i:
 			}
 		}
 	}
 
 	// Select from all the channels
 	wg.Add(len(channels))
-    for _,c := range channels {
-    	go multiplex(c)
+	for _, c := range channels {
+		go multiplex(c)
 	}
 
 	// Wait for all the reads to complete
@@ -161,46 +200,56 @@ func FanInChannel(done <-chan interface{}, channels ... <-chan interface{}) <-ch
 		wg.Wait()
 		close(multiplexedStream)
 	}()
-    return multiplexedStream
+	return multiplexedStream
 }
 
 // TeeChannel take the input from the incoming channel and split into two outgoing channels
 // similar to the UNIX tee command.
 // pp.120
 func TeeChannel(done <-chan interface{}, in <- chan interface{}) (<-chan interface{}, <-chan interface{}) {
-	out1 := make(chan interface{})
-	out2 := make(chan interface{})
+	return TeeTChannel(done, in)
+}
+
+// TeeTChannel is TeeChannel for a stream of any type.
+func TeeTChannel[T any, D any](done <-chan D, in <-chan T) (<-chan T, <-chan T) {
+	out1 := make(chan T)
+	out2 := make(chan T)
 	go func() {
 		defer func() {
 			close(out1)
 			close(out2)
 		}()
-		orDone := OrDoneChannel
+		orDone := OrDoneTChannel[T, D]
 		for val := range orDone(done, in) {
 			var out1, out2 = out1, out2 // shadow vars on purpose
 			for i := 0; i < 2; i++ {
 				select {
 				case <-done:
-				case out1<-val:
+				case out1 <- val:
 					out1 = nil
-				case out2<-val:
+				case out2 <- val:
 					out2 = nil
 				}
 			}
 		}
 	}()
-    return out1, out2
+	return out1, out2
 }
 
 // Bridging multiple channels
 // pp.122-123
 func BridgeChannel(done <-chan interface{}, chanStream <- chan <- chan interface{}) <-chan interface{} {
-	orDone := OrDoneChannel
-	valStream := make(chan interface{})
+	return BridgeTChannel(done, chanStream)
+}
+
+// BridgeTChannel is BridgeChannel for channels of any type.
+func BridgeTChannel[T any, D any](done <-chan D, chanStream <-chan <-chan T) <-chan T {
+	orDone := OrDoneTChannel[T, D]
+	valStream := make(chan T)
 	go func() {
 		defer close(valStream)
 		for {
-			var stream <-chan interface{}
+			var stream <-chan T
 			select {
 			case maybeStream, ok := <-chanStream:
 				if ok == false {
@@ -226,18 +275,7 @@ func BridgeChannel(done <-chan interface{}, chanStream <- chan <- chan interface
 // This version uses the generic interface{} which has a minor cost of conversion.
 // pp.104
 func GeneratorToChannel(done <-chan interface{}, slice ...interface{}) <- chan interface{}{
-	interfaceChannel := make(chan interface{}, len(slice))
-	go func() {
-		defer close(interfaceChannel)
-		for _, i := range slice {
-			select {
-			case <-done:
-				return
-			case interfaceChannel <- i:
-			}
-		}
-	}()
-	return interfaceChannel
+	return GeneratorToTChannel(done, slice...)
 }
 
 // I keep thinking that I should be able to pass in an []string to a fn which is declared
@@ -262,28 +300,35 @@ func GeneratorFromStringArrayToChannel(done <-chan interface{}, slice []string)
 // Will limit the number of items passed along in the channel to "limit"
 // This is to prevent downstream process from being flooded.
 func BufferChannel(done <-chan interface{}, in <- chan interface{}, limit int) <- chan interface{}{
-	orDone := OrDoneChannel
-	interfaceChannel := make(chan interface{}, limit)
+	return BufferTChannel(done, in, limit)
+}
+
+// BufferTChannel is BufferChannel for a stream of any type.
+func BufferTChannel[T any, D any](done <-chan D, in <-chan T, limit int) <-chan T {
+	orDone := OrDoneTChannel[T, D]
+	theStream := make(chan T, limit)
 
 	go func() {
 		defer func() {
 			// clean up the channels we create.
-			close(interfaceChannel)
+			close(theStream)
 		}()
 
 		for val := range orDone(done, in) {
 			select {
 			case <-done:
 				return
-			case interfaceChannel <- val:
+			case theStream <- val:
 			}
 		}
 	}()
 
-	return interfaceChannel
+	return theStream
 }
 
 // ToTChannel Take an interface channel and convert it to a type T channel
+// done stays a <-chan interface{} so ToTChannel[T] can be used as a func value,
+// CheckedToTChannel takes a done channel of any type, or see DoneFromContext.
 func ToTChannel [T any] (done <-chan interface{}, valueStream <-chan interface{}) <-chan T {
 	theStream := make(chan T)
 	go func() {
@@ -298,4 +343,3 @@ func ToTChannel [T any] (done <-chan interface{}, valueStream <-chan interface{}
 	}()
 	return theStream
 }
-
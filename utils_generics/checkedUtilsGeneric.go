@@ -0,0 +1,176 @@
+package utils_generics
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// ToTChannel panics on a value that is not a T, which takes the whole
+// process down.  CheckedToTChannel sends those values to an error channel
+// instead, after trying any converters it was given.
+//
+//	ints, errs := CheckedToTChannel(done, values, ConvertNumber[int](), ConvertFromString[int]())
+//
+// would pass along 1, int64(2) and "3" as ints, and send a *TypeMismatchError
+// for 1.5 or "three".
+
+// ErrNoConversion is returned by a Converter for a value it does not convert.
+var ErrNoConversion = errors.New("utils_generics: no conversion")
+
+// TypeMismatchError is a value that was not the type a stage wanted.
+type TypeMismatchError struct {
+	Value    interface{}
+	Actual   reflect.Type // the dynamic type of Value, nil if Value is nil
+	Expected reflect.Type
+	Err      error // why a converter could not convert it, if one tried
+}
+
+func newTypeMismatchError[T any](v interface{}, err error) *TypeMismatchError {
+	if errors.Is(err, ErrNoConversion) {
+		err = nil
+	}
+	return &TypeMismatchError{Value: v, Actual: reflect.TypeOf(v), Expected: reflect.TypeFor[T](), Err: err}
+}
+
+func (e *TypeMismatchError) Error() string {
+	actual := "<nil>"
+	if e.Actual != nil {
+		actual = e.Actual.String()
+	}
+	if e.Err != nil {
+		return fmt.Sprintf("utils_generics: %s is not a %v: %v", actual, e.Expected, e.Err)
+	}
+	return fmt.Sprintf("utils_generics: %s is not a %v", actual, e.Expected)
+}
+
+func (e *TypeMismatchError) Unwrap() error {
+	return e.Err
+}
+
+// Converter converts a value that is not a T into a T, or returns an error,
+// ErrNoConversion for a value it does not handle.
+type Converter[T any] func(v interface{}) (T, error)
+
+// toT returns v as a T, trying the converters in order if it is not a T.
+func toT[T any](v interface{}, converters []Converter[T]) (T, error) {
+	if t, ok := v.(T); ok {
+		return t, nil
+	}
+	err := ErrNoConversion
+	for _, convert := range converters {
+		t, convertErr := convert(v)
+		if convertErr == nil {
+			return t, nil
+		}
+		// keep the most useful reason.
+		if errors.Is(err, ErrNoConversion) {
+			err = convertErr
+		}
+	}
+	var t T
+	return t, newTypeMismatchError[T](v, err)
+}
+
+// CheckedToTChannel is ToTChannel sending a *TypeMismatchError for each value
+// that is not a T, and that none of the converters could convert, rather than
+// panicking.  The error channel is closed along with the values channel.  It
+// must be read, the stage waits for each error to be taken before carrying on.
+func CheckedToTChannel[T any, D any](done <-chan D, valueStream <-chan interface{}, converters ...Converter[T]) (<-chan T, <-chan error) {
+	return checkedToTChannel(done, valueStream, converters, func(v interface{}, err error) error {
+		return err
+	})
+}
+
+// ToTDeadLetterChannel is CheckedToTChannel sending a DeadLetter, with stage
+// as its Stage and the *TypeMismatchError as its Err, for each value that is
+// not a T and cannot be converted.  The dead-letter channel is closed along
+// with the values channel.  It must be read, the stage waits for each dead
+// letter to be taken before carrying on.
+func ToTDeadLetterChannel[T any, D any](done <-chan D, valueStream <-chan interface{}, stage string, converters ...Converter[T]) (<-chan T, <-chan DeadLetter) {
+	return checkedToTChannel(done, valueStream, converters, func(v interface{}, err error) DeadLetter {
+		return newDeadLetter(stage, v, err, 1)
+	})
+}
+
+// checkedToTChannel converts each value with toT, sending failed(v, err) for
+// the values it cannot convert.
+func checkedToTChannel[T any, E any, D any](done <-chan D, valueStream <-chan interface{}, converters []Converter[T], failed func(v interface{}, err error) E) (<-chan T, <-chan E) {
+	theStream := make(chan T)
+	failStream := make(chan E)
+	go func() {
+		defer close(failStream)
+		defer close(theStream)
+		for v := range OrDoneTChannel(done, valueStream) {
+			t, err := toT(v, converters)
+			if err != nil {
+				select {
+				case <-done:
+					return
+				case failStream <- failed(v, err):
+				}
+				continue
+			}
+			select {
+			case <-done:
+				return
+			case theStream <- t:
+			}
+		}
+	}()
+	return theStream, failStream
+}
+
+// ConvertNumber converts any integer or floating point value to a T, as long
+// as it fits exactly: int64(7) and 7.0 convert to int, 7.5 and 300 do not
+// convert to int8.
+func ConvertNumber[T Number]() Converter[T] {
+	return func(v interface{}) (T, error) {
+		var t T
+		rv := reflect.ValueOf(v)
+		exact := false
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			i := rv.Int()
+			t = T(i)
+			exact = int64(t) == i && (t < 0) == (i < 0)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			u := rv.Uint()
+			t = T(u)
+			exact = uint64(t) == u && t >= 0
+		case reflect.Float32, reflect.Float64:
+			f := rv.Float()
+			t = T(f)
+			exact = float64(t) == f
+		default:
+			return t, ErrNoConversion
+		}
+		if exact == false {
+			return 0, fmt.Errorf("%v does not fit", v)
+		}
+		return t, nil
+	}
+}
+
+// ConvertFromString parses a string, or a named string type, as a T with strconv.
+func ConvertFromString[T Number]() Converter[T] {
+	return func(v interface{}) (T, error) {
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.String {
+			return 0, ErrNoConversion
+		}
+		s := rv.String()
+		typ := reflect.TypeFor[T]()
+		switch typ.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			i, err := strconv.ParseInt(s, 10, typ.Bits())
+			return T(i), err
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			u, err := strconv.ParseUint(s, 10, typ.Bits())
+			return T(u), err
+		}
+		f, err := strconv.ParseFloat(s, typ.Bits())
+		return T(f), err
+	}
+}
@@ -0,0 +1,80 @@
+package utils_generics
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestCheckedToTChannel(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	values, errs := CheckedToTChannel[int](done, GeneratorToChannel(done, 1, "two", nil, 3))
+	result, errList := collectBoth(values, errs)
+	if !IntArrayEquals(result, []int{1, 3}) {
+		t.Fatalf("expected [1 3], \n got %v", result)
+	}
+	if len(errList) != 2 {
+		t.Fatalf("expected 2 errors, got %v", errList)
+	}
+	fmt.Printf("%v\n%v\n", errList[0], errList[1])
+
+	var mismatch *TypeMismatchError
+	if errors.As(errList[0], &mismatch) == false {
+		t.Fatalf("expected a TypeMismatchError, got %T", errList[0])
+	}
+	if mismatch.Value != "two" || mismatch.Actual != reflect.TypeFor[string]() || mismatch.Expected != reflect.TypeFor[int]() {
+		t.Fatalf("unexpected %+v", mismatch)
+	}
+	if errList[1].Error() != "utils_generics: <nil> is not a int" {
+		t.Fatalf("unexpected error %q", errList[1].Error())
+	}
+}
+
+func TestCheckedToTChannelConverters(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	in := GeneratorToChannel(done, 1, int64(2), "3", 4.0, uint8(5), 6.5, "seven", int64(1)<<40)
+	values, errs := CheckedToTChannel(done, in, ConvertNumber[int32](), ConvertFromString[int32]())
+	result, errList := collectBoth(values, errs)
+	if fmt.Sprint(result) != "[1 2 3 4 5]" {
+		t.Fatalf("expected [1 2 3 4 5], \n got %v", result)
+	}
+	// 6.5 and 1<<40 do not fit, seven does not parse.
+	if len(errList) != 3 {
+		t.Fatalf("expected 3 errors, got %v", errList)
+	}
+	if errors.Is(errList[1], strconv.ErrSyntax) == false {
+		t.Fatalf("expected the strconv error for seven, got %v", errList[1])
+	}
+	for _, err := range errList {
+		fmt.Printf("%v\n", err)
+	}
+}
+
+func TestConvertNumber(t *testing.T) {
+	toInt8 := ConvertNumber[int8]()
+	for _, v := range []interface{}{int64(-128), uint(127), float32(-3)} {
+		if _, err := toInt8(v); err != nil {
+			t.Fatalf("expected %v to convert, got %v", v, err)
+		}
+	}
+	for _, v := range []interface{}{300, uint64(1) << 63, 1.5, "1"} {
+		if i, err := toInt8(v); err == nil {
+			t.Fatalf("expected %v not to convert, got %v", v, i)
+		}
+	}
+
+	toUint := ConvertNumber[uint]()
+	if u, err := toUint(-1); err == nil {
+		t.Fatalf("expected -1 not to convert, got %v", u)
+	}
+	toFloat := ConvertNumber[float64]()
+	if f, err := toFloat(7); err != nil || f != 7 {
+		t.Fatalf("expected 7, got %v, %v", f, err)
+	}
+}
@@ -0,0 +1,137 @@
+package utils_generics
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Dead letters are the items a stage gave up on, kept so they can be looked
+// at and replayed later rather than lost.
+//
+// RetryChannel, CircuitBreakerChannel, MapErrChannel and ToTDeadLetterChannel
+// send theirs on a dead-letter channel, named after the stage they are given.
+// Added to a Pipeline, a stage's DeadLetters get the stage's name if they
+// were given none.  WriteDeadLetters saves them as JSON lines, and
+// ReplayDeadLettersChannel reads them back in.
+//
+//	values, failed := AddStage2(p, "price", func(done <-chan interface{}) (<-chan Price, <-chan DeadLetter) {
+//		return RetryChannel(done, orders, "", lookupPrice, RetryOptions{})
+//	})
+//	file, _ := os.OpenFile("dead.jsonl", os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+//	go WriteDeadLetters(p.Done(), failed, file)
+//
+// and later
+//
+//	orders, errs := ReplayDeadLettersChannel[Order](done, file, "price")
+
+// DeadLetter is an item a stage gave up on, and why.
+type DeadLetter struct {
+	Stage    string
+	Time     time.Time
+	Item     interface{}
+	Err      error // the last error
+	Attempts int
+}
+
+func newDeadLetter(stage string, item interface{}, err error, attempts int) DeadLetter {
+	return DeadLetter{Stage: stage, Time: time.Now(), Item: item, Err: err, Attempts: attempts}
+}
+
+// stageDeadLetter gives a DeadLetter coming out of the stage the stage's name, if it has none.
+func stageDeadLetter[T any](s *stage, v T) T {
+	if letter, ok := any(v).(DeadLetter); ok && letter.Stage == "" {
+		letter.Stage = s.name
+		return any(letter).(T)
+	}
+	return v
+}
+
+// deadLetterJSON is one line of a dead-letter file.
+type deadLetterJSON struct {
+	Stage    string          `json:"stage,omitempty"`
+	Time     time.Time       `json:"time"`
+	Attempts int             `json:"attempts"`
+	Error    string          `json:"error"`
+	Item     json.RawMessage `json:"item"`
+}
+
+// WriteDeadLetters is a sink writing each DeadLetter to w as a line of JSON,
+// until the channel is closed.  Items that cannot be marshalled are written
+// as their %v string.  It returns the first error writing to w.
+func WriteDeadLetters[D any](done <-chan D, deadLetters <-chan DeadLetter, w io.Writer, timeout ...time.Duration) error {
+	bw := bufio.NewWriter(w)
+	err := ForEach(done, deadLetters, func(letter DeadLetter) error {
+		item, err := json.Marshal(letter.Item)
+		if err != nil {
+			item, _ = json.Marshal(fmt.Sprint(letter.Item))
+		}
+		line := deadLetterJSON{
+			Stage:    letter.Stage,
+			Time:     letter.Time,
+			Attempts: letter.Attempts,
+			Item:     item,
+		}
+		if letter.Err != nil {
+			line.Error = letter.Err.Error()
+		}
+		b, err := json.Marshal(line)
+		if err != nil {
+			return err
+		}
+		if _, err = bw.Write(append(b, '\n')); err != nil {
+			return err
+		}
+		// flush once there is nothing waiting, so the file is up to date.
+		if len(deadLetters) == 0 {
+			return bw.Flush()
+		}
+		return nil
+	}, timeout...)
+	if flushErr := bw.Flush(); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
+// ReplayDeadLettersChannel reads the dead letters written by WriteDeadLetters
+// and sends their items, as T, back into a pipeline.  Only the items from
+// stage are sent, or every item if stage is "".  It stops at the first line
+// it cannot read, sending the error on the error channel, which is buffered so
+// it can be read once the values channel is closed.
+func ReplayDeadLettersChannel[T any, D any](done <-chan D, r io.Reader, stage string) (<-chan T, <-chan error) {
+	valStream := make(chan T)
+	errStream := make(chan error, 1)
+	go func() {
+		defer close(errStream)
+		defer close(valStream)
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(nil, 16*1024*1024)
+		for lineNumber := 1; scanner.Scan(); lineNumber++ {
+			var line deadLetterJSON
+			if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+				errStream <- fmt.Errorf("utils_generics: dead letter line %d: %w", lineNumber, err)
+				return
+			}
+			if stage != "" && line.Stage != stage {
+				continue
+			}
+			var v T
+			if err := json.Unmarshal(line.Item, &v); err != nil {
+				errStream <- fmt.Errorf("utils_generics: dead letter line %d: %w", lineNumber, err)
+				return
+			}
+			select {
+			case <-done:
+				return
+			case valStream <- v:
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			errStream <- err
+		}
+	}()
+	return valStream, errStream
+}
@@ -0,0 +1,123 @@
+package utils_generics
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestMapErrChannel(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	values, deadLetters := MapErrChannel(done, GeneratorToTChannel(done, "1", "x", "3"), "atoi", strconv.Atoi)
+	collected := make(chan []DeadLetter)
+	go func() {
+		letters, _ := Collect(done, deadLetters)
+		collected <- letters
+	}()
+	result, _ := Collect(done, values)
+	letters := <-collected
+
+	if !IntArrayEquals(result, []int{1, 3}) {
+		t.Fatalf("expected [1 3], \n got %v", result)
+	}
+	if len(letters) != 1 || letters[0].Item != "x" || letters[0].Stage != "atoi" || letters[0].Time.IsZero() {
+		t.Fatalf("expected a dead letter for x, got %+v", letters)
+	}
+}
+
+func TestToTDeadLetterChannel(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	values, deadLetters := ToTDeadLetterChannel(done, GeneratorToChannel(done, 1, "two", 3.0), "toInt", ConvertNumber[int]())
+	var result []int
+	for values != nil || deadLetters != nil {
+		select {
+		case v, ok := <-values:
+			if ok == false {
+				values = nil
+				continue
+			}
+			result = append(result, v)
+		case letter, ok := <-deadLetters:
+			if ok == false {
+				deadLetters = nil
+				continue
+			}
+			fmt.Printf("%v\n", letter.Err)
+			if letter.Item != "two" || letter.Stage != "toInt" || letter.Err.Error() != "utils_generics: string is not a int" {
+				t.Fatalf("unexpected dead letter %+v", letter)
+			}
+		}
+	}
+	if !IntArrayEquals(result, []int{1, 3}) {
+		t.Fatalf("expected [1 3], \n got %v", result)
+	}
+}
+
+type order struct {
+	ID    int
+	Price float64
+}
+
+func TestDeadLettersRoundTrip(t *testing.T) {
+	errNoPrice := errors.New("no price")
+	price := func(o order) (order, error) {
+		if o.ID%2 == 0 {
+			return o, errNoPrice
+		}
+		o.Price = 9.99
+		return o, nil
+	}
+
+	p := NewPipeline("orders")
+	orders := AddStage(p, "orders", func(done <-chan interface{}) <-chan order {
+		return GeneratorToTChannel(done, order{ID: 1}, order{ID: 2}, order{ID: 3}, order{ID: 4})
+	})
+	priced, failed := AddStage2(p, "price", func(done <-chan interface{}) (<-chan order, <-chan DeadLetter) {
+		return MapErrChannel(done, orders, "", price)
+	}, Inputs(orders))
+	p.Start()
+
+	var file bytes.Buffer
+	written := make(chan error)
+	go func() { written <- WriteDeadLetters(p.Done(), failed, &file) }()
+	if count, _ := Count(p.Done(), priced); count != 2 {
+		t.Fatalf("expected 2 priced orders, got %d", count)
+	}
+	if err := <-written; err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	p.Wait()
+
+	fmt.Print(file.String())
+	lines := strings.Split(strings.TrimSpace(file.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"stage":"price"`) || !strings.Contains(lines[0], `"error":"no price"`) {
+		t.Fatalf("expected 2 dead letters from price, got %v", lines)
+	}
+
+	done := make(chan interface{})
+	defer close(done)
+	replayed, errs := ReplayDeadLettersChannel[order](done, strings.NewReader(file.String()), "price")
+	result, _ := Collect(done, replayed)
+	if len(result) != 2 || result[0].ID != 2 || result[1].ID != 4 {
+		t.Fatalf("expected orders 2 and 4, got %v", result)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// another stage's dead letters are skipped, and a bad line stops the replay.
+	replayed, errs = ReplayDeadLettersChannel[order](done, strings.NewReader(file.String()+"not json\n"), "other")
+	if result, _ = Collect(done, replayed); len(result) != 0 {
+		t.Fatalf("expected nothing, got %v", result)
+	}
+	if err := <-errs; err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Fatalf("expected an error for line 3, got %v", err)
+	}
+}
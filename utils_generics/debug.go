@@ -0,0 +1,152 @@
+package utils_generics
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// A debug page for running pipelines, in the spirit of net/http/pprof.
+//
+//	http.Handle("/debug/pipelines", DebugHandler())
+//
+// GET lists every running Pipeline and its stages, add ?format=json for JSON.
+// The goroutines for each stage are counted from their pprof labels, so they
+// include the goroutines of the utilities the stage was built from.
+// POST with cancel=<id> stops that Pipeline by closing its done channel.
+//
+//	curl -d cancel=3 http://localhost:8080/debug/pipelines
+
+var (
+	registryMu sync.Mutex
+	registry   = make(map[uint64]*Pipeline)
+)
+
+// registerPipeline is called by Start.
+func registerPipeline(p *Pipeline) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[p.id] = p
+}
+
+// unregisterPipeline is called once every stage of the Pipeline has finished.
+func unregisterPipeline(p *Pipeline) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	delete(registry, p.id)
+}
+
+// RunningPipelines returns every Pipeline that has been started and still
+// has stages running, oldest first.
+func RunningPipelines() []*Pipeline {
+	registryMu.Lock()
+	pipelines := make([]*Pipeline, 0, len(registry))
+	for _, p := range registry {
+		pipelines = append(pipelines, p)
+	}
+	registryMu.Unlock()
+
+	sort.Slice(pipelines, func(i, j int) bool { return pipelines[i].id < pipelines[j].id })
+	return pipelines
+}
+
+// stopping returns true once Stop has been called.
+func (p *Pipeline) stopping() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// debugPipeline is what the debug page shows for each Pipeline.
+type debugPipeline struct {
+	ID       uint64
+	Name     string
+	Stopping bool
+	Stages   []StageStatus
+}
+
+// DebugHandler serves the debug page, see above.
+func DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			debugList(w, r)
+		case http.MethodPost:
+			debugCancel(w, r)
+		default:
+			w.Header().Set("Allow", "GET, HEAD, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func debugList(w http.ResponseWriter, r *http.Request) {
+	var pipelines []debugPipeline
+	goroutines := stageGoroutines()
+	for _, p := range RunningPipelines() {
+		stages := p.Stages()
+		for i := range stages {
+			stages[i].Goroutines = goroutines[p.id][stages[i].Name]
+		}
+		pipelines = append(pipelines, debugPipeline{
+			ID:       p.id,
+			Name:     p.name,
+			Stopping: p.stopping(),
+			Stages:   stages,
+		})
+	}
+
+	if r.FormValue("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(pipelines)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	now := time.Now()
+	fmt.Fprintf(w, "%d running pipelines\n", len(pipelines))
+	for _, p := range pipelines {
+		state := "running"
+		if p.Stopping {
+			state = "stopping"
+		}
+		fmt.Fprintf(w, "\npipeline %d %q %s\n", p.ID, p.Name, state)
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "\tstage\tstate\tgoroutines\tbuffer\tlast item")
+		for _, s := range p.Stages {
+			lastItem := "never"
+			if s.LastItem.IsZero() == false {
+				lastItem = now.Sub(s.LastItem).Round(time.Millisecond).String() + " ago"
+			}
+			fmt.Fprintf(tw, "\t%s\t%v\t%d\t%d/%d\t%s\n", s.Name, s.State, s.Goroutines, s.BufferLen, s.BufferCap, lastItem)
+		}
+		tw.Flush()
+	}
+}
+
+func debugCancel(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.FormValue("cancel"), 10, 64)
+	if err != nil {
+		http.Error(w, "cancel must be the id of a pipeline", http.StatusBadRequest)
+		return
+	}
+
+	registryMu.Lock()
+	p, ok := registry[id]
+	registryMu.Unlock()
+	if ok == false {
+		http.Error(w, fmt.Sprintf("no running pipeline %d", id), http.StatusNotFound)
+		return
+	}
+
+	p.Stop()
+	fmt.Fprintf(w, "cancelled pipeline %d %q\n", p.id, p.name)
+}
@@ -0,0 +1,80 @@
+package utils_generics
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDebugHandler(t *testing.T) {
+	p := NewPipeline("stuck")
+	ones := AddStage(p, "repeat", func(done <-chan interface{}) <-chan interface{} {
+		return RepeatValueChannel(done, 1)
+	})
+	buffered := AddStage(p, "buffer", func(done <-chan interface{}) <-chan interface{} {
+		return BufferChannel(done, ones, 3)
+	}, Inputs(ones))
+	p.Start()
+	<-buffered // take one, then nobody reads any more.
+	time.Sleep(50 * time.Millisecond)
+
+	handler := DebugHandler()
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/debug/pipelines", nil))
+	body := recorder.Body.String()
+	fmt.Print(body)
+	if !strings.Contains(body, fmt.Sprintf("pipeline %d \"stuck\" running", p.ID())) || !strings.Contains(body, "3/3") {
+		t.Fatalf("expected the pipeline with a full buffer, got\n%s", body)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/debug/pipelines?format=json", nil))
+	var pipelines []debugPipeline
+	if err := json.Unmarshal(recorder.Body.Bytes(), &pipelines); err != nil {
+		t.Fatalf("bad json %v", err)
+	}
+	found := false
+	for _, dp := range pipelines {
+		if dp.ID == p.ID() {
+			found = true
+			if len(dp.Stages) != 2 || dp.Stages[0].LastItem.IsZero() {
+				t.Fatalf("expected two stages with a last item, got %+v", dp.Stages)
+			}
+		}
+	}
+	if found == false {
+		t.Fatalf("pipeline %d not in %s", p.ID(), recorder.Body.String())
+	}
+
+	cancel := func(id string) int {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest("POST", "/debug/pipelines", strings.NewReader(url.Values{"cancel": {id}}.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		handler.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+	if code := cancel("nope"); code != 400 {
+		t.Fatalf("expected 400, got %d", code)
+	}
+	if code := cancel(strconv.FormatUint(p.ID(), 10)); code != 200 {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	p.Wait()
+
+	// once it has finished it drops off the list.
+	time.Sleep(10 * time.Millisecond)
+	for _, running := range RunningPipelines() {
+		if running == p {
+			t.Fatalf("pipeline %d should no longer be running", p.ID())
+		}
+	}
+	if code := cancel(strconv.FormatUint(p.ID(), 10)); code != 404 {
+		t.Fatalf("expected 404, got %d", code)
+	}
+}
@@ -0,0 +1,141 @@
+package utils_generics
+
+import (
+	"context"
+	"sync"
+)
+
+// The generic stages take their done channel as a <-chan D of any type, so a
+// context's Done(), a <-chan struct{} or any other stop channel is passed
+// straight in, with nothing in between to wait on it:
+//
+//	values := TakeTChannel(ctx.Done(), RepeatValueTChannel(ctx.Done(), 1), 10)
+//
+// Or2TChannel combines two done channels of different types, OrAnyChannel
+// any number of them:
+//
+//	done := Or2TChannel(ctx.Done(), p.Done())
+//	done := OrAnyChannel(DoneOf(ctx.Done()), DoneOf(p.Done()), DoneOf(stop))
+//
+// ToTChannel and the interface{} functions still take a <-chan interface{},
+// so ToTChannel[int] can still be used as a func value, CheckedToTChannel
+// takes a done channel of any type.  DoneFromContext and DoneFrom bridge to
+// them:
+//
+//	done, cancel := DoneFromContext(ctx)
+//	defer cancel()
+//	ints := ToTChannel[int](done, values)
+//
+// The done channels these return only close, like the ones they come from.
+
+// Or2TChannel is OrTChannel for two channels of different types.  Like
+// OrTChannel it closes once either of them is closed or sends, taking that
+// value, so it is meant for done channels, which only close.  It returns nil
+// if both are nil.
+func Or2TChannel[A any, B any](a <-chan A, b <-chan B) <-chan interface{} {
+	if a == nil && b == nil {
+		return nil
+	}
+	orDone := make(chan interface{})
+	go func() {
+		defer close(orDone)
+		select {
+		case <-a:
+		case <-b:
+		}
+	}()
+	return orDone
+}
+
+// DoneSignal is a done channel of any type, see DoneOf.
+type DoneSignal interface {
+	// wait returns true once the done channel closes or sends, or false
+	// once stop closes.
+	wait(stop <-chan interface{}) bool
+	isNil() bool
+}
+
+type doneSignal[D any] <-chan D
+
+func (done doneSignal[D]) wait(stop <-chan interface{}) bool {
+	select {
+	case <-done:
+		return true
+	case <-stop:
+		return false
+	}
+}
+
+func (done doneSignal[D]) isNil() bool {
+	return done == nil
+}
+
+// DoneOf wraps done, a channel of any type, for OrAnyChannel.
+func DoneOf[D any](done <-chan D) DoneSignal {
+	return doneSignal[D](done)
+}
+
+// OrAnyChannel is OrTChannel for done channels of any types, mixed together.
+// It closes once any of them is closed or sends, taking that value.  Nil
+// channels are ignored, as in a select, it returns nil if they all are.
+// Each channel is waited on by a goroutine of its own until then.
+func OrAnyChannel(signals ...DoneSignal) <-chan interface{} {
+	var waiting []DoneSignal
+	for _, signal := range signals {
+		if signal != nil && signal.isNil() == false {
+			waiting = append(waiting, signal)
+		}
+	}
+	if len(waiting) == 0 {
+		return nil
+	}
+
+	orDone := make(chan interface{})
+	var once sync.Once
+	for _, signal := range waiting {
+		go func() {
+			if signal.wait(orDone) {
+				once.Do(func() { close(orDone) })
+			}
+		}()
+	}
+	return orDone
+}
+
+// DoneFrom returns a done channel that closes once signal, a done channel of
+// any type, is closed or sends, for the functions that take a
+// <-chan interface{}.  A goroutine waits on signal until then, cancel closes
+// done and lets it go, so call it once done is no longer needed, as with
+// context.WithCancel.
+func DoneFrom[D any](signal <-chan D) (done <-chan interface{}, cancel func()) {
+	orDone := make(chan interface{})
+	var once sync.Once
+	cancel = func() {
+		once.Do(func() { close(orDone) })
+	}
+	go func() {
+		select {
+		case <-signal:
+			cancel()
+		case <-orDone:
+		}
+	}()
+	return orDone, cancel
+}
+
+// DoneFromContext returns a done channel that closes once ctx is done, for
+// the functions that take a <-chan interface{}.  It uses context.AfterFunc,
+// so there is no goroutine waiting on ctx, cancel closes done and releases
+// the AfterFunc, as with context.WithCancel.
+func DoneFromContext(ctx context.Context) (done <-chan interface{}, cancel func()) {
+	ctxDone := make(chan interface{})
+	var once sync.Once
+	closeDone := func() {
+		once.Do(func() { close(ctxDone) })
+	}
+	stop := context.AfterFunc(ctx, closeDone)
+	return ctxDone, func() {
+		stop()
+		closeDone()
+	}
+}
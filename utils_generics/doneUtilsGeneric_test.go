@@ -0,0 +1,159 @@
+package utils_generics
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// closesSoon fails if done does not close within a second.
+func closesSoon(t *testing.T, done <-chan interface{}) {
+	t.Helper()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("expected done to close")
+	}
+}
+
+// staysOpen fails if done closes.
+func staysOpen(t *testing.T, done <-chan interface{}) {
+	t.Helper()
+	select {
+	case <-done:
+		t.Fatalf("expected done to stay open")
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	values := TakeTChannel(ctx.Done(), RepeatValueTChannel(ctx.Done(), 1), 1000000)
+	<-values
+	cancel()
+	if err := Drain(make(chan struct{}), values, time.Second); err != nil {
+		t.Fatalf("expected the stages to stop, got %v", err)
+	}
+
+	// and a plain stop channel.
+	stop := make(chan struct{})
+	doubled := MapChannel(stop, RangeChannel(stop, 0, 1000000, 1), func(v int) int { return 2 * v })
+	if v := <-doubled; v != 0 {
+		t.Fatalf("expected 0, got %d", v)
+	}
+	close(stop)
+	Drain(stop, doubled)
+}
+
+func TestOr2TChannel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := NewPipeline("or")
+	p.Start()
+
+	done := Or2TChannel(ctx.Done(), p.Done())
+	staysOpen(t, done)
+	p.Stop()
+	closesSoon(t, done)
+
+	// combined again with a third type.
+	stop := make(chan bool)
+	done = Or2TChannel(Or2TChannel(ctx.Done(), (<-chan int)(nil)), stop)
+	staysOpen(t, done)
+	cancel()
+	closesSoon(t, done)
+
+	if Or2TChannel[struct{}, int](nil, nil) != nil {
+		t.Fatalf("expected nil for two nil channels")
+	}
+}
+
+func TestOrAnyChannel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := NewPipeline("or any")
+	p.Start()
+	stop := make(chan bool)
+
+	done := OrAnyChannel(DoneOf(ctx.Done()), DoneOf(p.Done()), DoneOf(stop), DoneOf((<-chan int)(nil)))
+	staysOpen(t, done)
+	close(stop)
+	closesSoon(t, done)
+	// the others have let go of their goroutines, closing them now is fine.
+	p.Stop()
+	cancel()
+
+	if OrAnyChannel() != nil || OrAnyChannel(DoneOf((<-chan struct{})(nil))) != nil {
+		t.Fatalf("expected nil for no channels to wait on")
+	}
+}
+
+func TestDoneFromContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done, stop := DoneFromContext(ctx)
+	defer stop()
+
+	// ToTChannel takes a <-chan interface{}.
+	values := ToTChannel[int](done, RepeatValueChannel(done, 1))
+	<-values
+	staysOpen(t, done)
+	cancel()
+	closesSoon(t, done)
+	for range values {
+	}
+
+	// cancel closes done without ctx, and can be called again.
+	done, stop = DoneFromContext(context.Background())
+	stop()
+	stop()
+	closesSoon(t, done)
+}
+
+func TestDoneFrom(t *testing.T) {
+	signal := make(chan struct{})
+	done, cancel := DoneFrom(signal)
+	defer cancel()
+	staysOpen(t, done)
+	close(signal)
+	closesSoon(t, done)
+
+	done, cancel = DoneFrom((<-chan struct{})(nil))
+	staysOpen(t, done)
+	cancel()
+	closesSoon(t, done)
+}
+
+func TestCheckedToTChannelContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ints, errs := CheckedToTChannel[int](ctx.Done(), RepeatValueTChannel[interface{}](ctx.Done(), 1))
+	if v := <-ints; v != 1 {
+		t.Fatalf("expected 1, got %d", v)
+	}
+	cancel()
+	for ints != nil || errs != nil {
+		select {
+		case _, ok := <-ints:
+			if ok == false {
+				ints = nil
+			}
+		case _, ok := <-errs:
+			if ok == false {
+				errs = nil
+			}
+		}
+	}
+}
+
+func TestWithDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := NewPipeline("with done", WithDone(ctx.Done()))
+	out := AddStage(p, "repeat", func(done <-chan interface{}) <-chan int {
+		return RepeatValueTChannel(done, 1)
+	})
+	p.Start()
+	<-out
+	cancel()
+	closesSoon(t, p.Done())
+	Drain(make(chan struct{}), out, time.Second)
+	p.Wait()
+}
@@ -0,0 +1,75 @@
+package utils_generics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Exporting the topology of a Pipeline as a Graphviz DOT graph.
+//
+//	p.WriteDOT(f)
+//	dot -Tsvg pipeline.dot > pipeline.svg
+//
+// The edges come from Inputs, a stage added without Inputs is drawn on its own.
+// With WithMetrics each stage is labelled with its counts as of the call, for
+// each of its outputs, and each edge with the items taken from the output it
+// comes from.
+
+// WriteDOT writes the Pipeline's stages and the edges between them as a DOT graph.
+func (p *Pipeline) WriteDOT(w io.Writer) error {
+	pm := p.Snapshot()
+	index := make(map[string]int, len(pm.Stages))
+	for i := range pm.Stages {
+		index[pm.Stages[i].Name] = i
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "digraph %s {\n", dotQuote(pm.Pipeline))
+	fmt.Fprintf(bw, "\trankdir=LR;\n\tnode [shape=box];\n")
+	for i := range pm.Stages {
+		sm := &pm.Stages[i]
+		label := fmt.Sprintf("%s\n%v", sm.Name, sm.State)
+		for j := range sm.Outputs {
+			om := &sm.Outputs[j]
+			if len(sm.Outputs) > 1 {
+				label += fmt.Sprintf("\nout%d", j+1)
+			}
+			label += fmt.Sprintf("\nin %d / out %d", om.ItemsIn, om.ItemsOut)
+			if om.BufferCap > 0 {
+				label += fmt.Sprintf("\nbuffer %d/%d", om.BufferLen, om.BufferCap)
+			}
+		}
+		fmt.Fprintf(bw, "\ts%d [label=%s];\n", i, dotQuote(label))
+	}
+	for i := range pm.Stages {
+		for j, input := range pm.Stages[i].Inputs {
+			from := index[input]
+			if outputs, port := pm.Stages[from].Outputs, pm.Stages[i].InputPorts[j]; port < len(outputs) {
+				label := fmt.Sprint(outputs[port].ItemsOut)
+				if len(outputs) > 1 {
+					label = fmt.Sprintf("out%d: %s", port+1, label)
+				}
+				fmt.Fprintf(bw, "\ts%d -> s%d [label=%s];\n", from, i, dotQuote(label))
+			} else {
+				fmt.Fprintf(bw, "\ts%d -> s%d;\n", from, i)
+			}
+		}
+	}
+	fmt.Fprintf(bw, "}\n")
+	return bw.Flush()
+}
+
+// DOT returns the Pipeline as a DOT graph, see WriteDOT.
+func (p *Pipeline) DOT() string {
+	var sb strings.Builder
+	p.WriteDOT(&sb)
+	return sb.String()
+}
+
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func dotQuote(s string) string {
+	return `"` + dotEscaper.Replace(s) + `"`
+}
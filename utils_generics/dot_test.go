@@ -0,0 +1,65 @@
+package utils_generics
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPipelineDOT(t *testing.T) {
+	p := NewPipeline("dot", WithMetrics())
+
+	names := []string{`tom`, `dick`, `harry`}
+	generated := AddStage(p, "generator", func(done <-chan interface{}) <-chan interface{} {
+		return GeneratorFromStringArrayToChannel(done, names)
+	})
+	out1, out2 := AddStage2(p, "tee", func(done <-chan interface{}) (<-chan interface{}, <-chan interface{}) {
+		return TeeChannel(done, generated)
+	}, Inputs(generated))
+	merged := AddStage(p, "fanIn", func(done <-chan interface{}) <-chan interface{} {
+		return FanInChannel(done, out1, out2)
+	}, Inputs(out1, out2))
+	p.Start()
+	count, _ := Count(p.Done(), merged)
+	p.Wait()
+
+	dot := p.DOT()
+	fmt.Print(dot)
+	if count != 6 {
+		t.Fatalf("expected 6 names, got %d", count)
+	}
+
+	expected := []string{
+		`digraph "dot" {`,
+		`s0 [label="generator\nfinished\nin 3 / out 3`,
+		`s0 -> s1 [label="3"];`,
+		`s1 [label="tee\nfinished\nout1\nin 3 / out 3\nout2\nin 3 / out 3"];`,
+		`s1 -> s2 [label="out1: 3"];`,
+		`s1 -> s2 [label="out2: 3"];`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(dot, line) {
+			t.Fatalf("expected %s in the graph", line)
+		}
+	}
+	// both outputs of tee feed fanIn.
+	if strings.Count(dot, "s1 -> s2") != 2 {
+		t.Fatalf("expected two edges from tee to fanIn")
+	}
+}
+
+func TestPipelineDOTWithoutMetrics(t *testing.T) {
+	p := NewPipeline("plain")
+	ones := AddStage(p, "repeat", func(done <-chan interface{}) <-chan interface{} {
+		return RepeatValueChannel(done, 1)
+	})
+	AddStage(p, "take", func(done <-chan interface{}) <-chan interface{} {
+		return TakeChannel(done, ones, 10)
+	}, Inputs(ones, make(chan interface{})))
+
+	dot := p.DOT()
+	fmt.Print(dot)
+	if !strings.Contains(dot, "s0 -> s1;") || strings.Contains(dot, " in ") || strings.Count(dot, "->") != 1 {
+		t.Fatalf("expected a single plain edge, got\n%s", dot)
+	}
+}
@@ -0,0 +1,124 @@
+package utils_generics
+
+import (
+	"math/rand"
+)
+
+// Sources for starting a pipeline. Like GeneratorToChannel they all close the
+// channel they return when the done channel is closed, or when they run out
+// of values.
+
+// Number is any of the built in integer or float types, or a type based on them.
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64
+}
+
+// Pair holds a key and its value, as sent by FromMapChannel.
+type Pair[K any, V any] struct {
+	Key   K
+	Value V
+}
+
+// GeneratorToTChannel, given a slice, convert it to a channel of the same type.
+// This is GeneratorToChannel without the conversion to interface{},
+// so any slice can be passed in with slice...
+func GeneratorToTChannel[T any, D any](done <-chan D, slice ...T) <-chan T {
+	theStream := make(chan T, len(slice))
+	go func() {
+		defer close(theStream)
+		for _, v := range slice {
+			select {
+			case <-done:
+				return
+			case theStream <- v:
+			}
+		}
+	}()
+	return theStream
+}
+
+// RangeChannel sends start, start+step, start+2*step ... stopping before end.
+// A negative step counts down to end, a zero step sends nothing.
+func RangeChannel[T Number, D any](done <-chan D, start, end, step T) <-chan T {
+	theStream := make(chan T)
+	go func() {
+		defer close(theStream)
+		var zero T
+		if step == zero {
+			return
+		}
+		up := step > zero
+		for v := start; (up && v < end) || (!up && v > end); {
+			select {
+			case <-done:
+				return
+			case theStream <- v:
+			}
+			next := v + step
+			// stop rather than wrap around at the limits of T.
+			if (up && next <= v) || (!up && next >= v) {
+				return
+			}
+			v = next
+		}
+	}()
+	return theStream
+}
+
+// IterateChannel sends seed, fn(seed), fn(fn(seed)) ... until you tell it to stop.
+func IterateChannel[T any, D any](done <-chan D, seed T, fn func(T) T) <-chan T {
+	theStream := make(chan T)
+	go func() {
+		defer close(theStream)
+		for v := seed; ; v = fn(v) {
+			select {
+			case <-done:
+				return
+			case theStream <- v:
+			}
+		}
+	}()
+	return theStream
+}
+
+// FromMapChannel sends every key/value pair in the map, in no particular order.
+// The pairs are copied before returning, so the map may be changed afterwards.
+func FromMapChannel[K comparable, V any, D any](done <-chan D, m map[K]V) <-chan Pair[K, V] {
+	pairs := make([]Pair[K, V], 0, len(m))
+	for k, v := range m {
+		pairs = append(pairs, Pair[K, V]{Key: k, Value: v})
+	}
+	return GeneratorToTChannel(done, pairs...)
+}
+
+// FromFuncChannel calls fn and sends the values it returns until fn returns false,
+// or you tell it to stop. The value returned with false is not sent.
+func FromFuncChannel[T any, D any](done <-chan D, fn func() (T, bool)) <-chan T {
+	theStream := make(chan T)
+	go func() {
+		defer close(theStream)
+		for {
+			v, ok := fn()
+			if ok == false {
+				return
+			}
+			select {
+			case <-done:
+				return
+			case theStream <- v:
+			}
+		}
+	}()
+	return theStream
+}
+
+// RandomChannel sends fn(r) infinitely, where r is a random source created from seed.
+// The same seed always gives the same stream, which makes for repeatable tests.
+//
+//	RandomChannel(done, 42, func(r *rand.Rand) int { return r.Intn(100) })
+func RandomChannel[T any, D any](done <-chan D, seed int64, fn func(r *rand.Rand) T) <-chan T {
+	r := rand.New(rand.NewSource(seed))
+	return FromFuncChannel(done, func() (T, bool) { return fn(r), true })
+}
@@ -0,0 +1,118 @@
+package utils_generics
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestGeneratorToTChannel(t *testing.T) {
+	generator := GeneratorToTChannel[string, interface{}]
+
+	done := make(chan interface{})
+	defer close(done)
+
+	names := []string{`tom`, `dick`, `harry`}
+	result, _ := Collect(done, generator(done, names...))
+	fmt.Printf("%v\n", result)
+	if len(result) != len(names) || result[2] != `harry` {
+		t.Fatalf("expected %v, \n got %v", names, result)
+	}
+}
+
+func TestRangeChannel(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	up, _ := Collect(done, RangeChannel(done, 0, 10, 3))
+	if !IntArrayEquals(up, []int{0, 3, 6, 9}) {
+		t.Fatalf("expected [0 3 6 9], \n got %v", up)
+	}
+
+	down, _ := Collect(done, RangeChannel(done, 5, 0, -2))
+	if !IntArrayEquals(down, []int{5, 3, 1}) {
+		t.Fatalf("expected [5 3 1], \n got %v", down)
+	}
+
+	if none, _ := Collect(done, RangeChannel(done, 0, 10, 0)); len(none) != 0 {
+		t.Fatalf("expected nothing for a zero step, got %v", none)
+	}
+
+	// must stop at the top of the type rather than wrap around.
+	bytes, _ := Collect(done, RangeChannel[uint8](done, 250, 255, 4))
+	if len(bytes) != 2 {
+		t.Fatalf("expected [250 254], \n got %v", bytes)
+	}
+
+	floats, _ := Collect(done, RangeChannel(done, 0.0, 1.0, 0.25))
+	fmt.Printf("%v\n", floats)
+	if len(floats) != 4 {
+		t.Fatalf("expected 4 values, got %v", floats)
+	}
+}
+
+func TestIterateChannel(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	double := func(v int) int { return v * 2 }
+	iterate := IterateChannel(done, 1, double)
+
+	var result []int
+	for i := 0; i < 5; i++ {
+		result = append(result, <-iterate)
+	}
+	if !IntArrayEquals(result, []int{1, 2, 4, 8, 16}) {
+		t.Fatalf("expected [1 2 4 8 16], \n got %v", result)
+	}
+}
+
+func TestFromMapChannel(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	ages := map[string]int{`tom`: 30, `dick`: 40, `harry`: 50}
+	pairs, _ := Collect(done, FromMapChannel(done, ages))
+	if len(pairs) != len(ages) {
+		t.Fatalf("expected %d pairs, got %v", len(ages), pairs)
+	}
+	for _, p := range pairs {
+		if ages[p.Key] != p.Value {
+			t.Fatalf("pair %v does not match the map", p)
+		}
+	}
+}
+
+func TestFromFuncChannel(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	i := 0
+	countTo3 := func() (int, bool) {
+		i++
+		return i, i <= 3
+	}
+	result, _ := Collect(done, FromFuncChannel(done, countTo3))
+	if !IntArrayEquals(result, []int{1, 2, 3}) {
+		t.Fatalf("expected [1 2 3], \n got %v", result)
+	}
+}
+
+func TestRandomChannel(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	intn := func(r *rand.Rand) int { return r.Intn(1000) }
+	first := RandomChannel(done, 42, intn)
+	second := RandomChannel(done, 42, intn)
+
+	var a, b []int
+	for i := 0; i < 10; i++ {
+		a = append(a, <-first)
+		b = append(b, <-second)
+	}
+	fmt.Printf("%v\n", a)
+	if !IntArrayEquals(a, b) {
+		t.Fatalf("same seed should give the same values %v, \n got %v", a, b)
+	}
+}
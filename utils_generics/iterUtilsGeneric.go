@@ -0,0 +1,125 @@
+package utils_generics
+
+import (
+	"iter"
+)
+
+// Adapters between Go 1.23 iterators and channels.
+//
+// ToSeq lets synchronous code range over a pipeline without spawning any more
+// goroutines, and the *Seq stages work on an iterator directly.  Going the
+// other way FromSeqChannel turns any iterator into the start of a pipeline.
+
+// FromPullChannel sends the values returned by next until it returns false,
+// or you tell it to stop. stop is always called when the channel is closed,
+// so the producer behind next gets to clean up.
+//
+// next is only called when the previous value has been taken, so a
+// producer from iter.Pull runs at most one value ahead of the pipeline.
+//
+//	next, stop := iter.Pull(seq)
+//	valueStream := FromPullChannel(done, next, stop)
+func FromPullChannel[T any, D any](done <-chan D, next func() (T, bool), stop func()) <-chan T {
+	theStream := make(chan T)
+	go func() {
+		defer close(theStream)
+		defer stop()
+		for {
+			v, ok := next()
+			if ok == false {
+				return
+			}
+			select {
+			case <-done:
+				return
+			case theStream <- v:
+			}
+		}
+	}()
+	return theStream
+}
+
+// FromSeqChannel sends every value of seq, pulling them lazily with iter.Pull.
+func FromSeqChannel[T any, D any](done <-chan D, seq iter.Seq[T]) <-chan T {
+	next, stop := iter.Pull(seq)
+	return FromPullChannel(done, next, stop)
+}
+
+// FromSeq2Channel sends every key/value of seq as a Pair, pulling them lazily with iter.Pull2.
+func FromSeq2Channel[K any, V any, D any](done <-chan D, seq iter.Seq2[K, V]) <-chan Pair[K, V] {
+	next, stop := iter.Pull2(seq)
+	return FromPullChannel(done, func() (Pair[K, V], bool) {
+		k, v, ok := next()
+		return Pair[K, V]{Key: k, Value: v}, ok
+	}, stop)
+}
+
+// ToSeq returns an iterator over the values in the stream.
+// Iteration stops when the stream or the done channel is closed.
+// Breaking out of the loop does not stop the producer, close done for that.
+func ToSeq[T any, D any](done <-chan D, valueStream <-chan T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for {
+			select {
+			case <-done:
+				return
+			case v, ok := <-valueStream:
+				if ok == false || yield(v) == false {
+					return
+				}
+			}
+		}
+	}
+}
+
+// ToSeq2 returns an iterator over the key/value pairs in the stream.
+func ToSeq2[K any, V any, D any](done <-chan D, pairStream <-chan Pair[K, V]) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for p := range ToSeq(done, pairStream) {
+			if yield(p.Key, p.Value) == false {
+				return
+			}
+		}
+	}
+}
+
+// TakeSeq yields only the first num values of seq, the iterator version of TakeChannel.
+func TakeSeq[T any](seq iter.Seq[T], num int) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		if num <= 0 {
+			return
+		}
+		i := 0
+		for v := range seq {
+			if yield(v) == false {
+				return
+			}
+			i++
+			if i >= num {
+				return
+			}
+		}
+	}
+}
+
+// MapSeq yields fn(v) for every value v of seq.
+func MapSeq[T any, U any](seq iter.Seq[T], fn func(T) U) iter.Seq[U] {
+	return func(yield func(U) bool) {
+		for v := range seq {
+			if yield(fn(v)) == false {
+				return
+			}
+		}
+	}
+}
+
+// FilterSeq yields only the values of seq for which pred returns true.
+func FilterSeq[T any](seq iter.Seq[T], pred func(T) bool) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for v := range seq {
+			if pred(v) && yield(v) == false {
+				return
+			}
+		}
+	}
+}
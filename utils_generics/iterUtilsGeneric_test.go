@@ -0,0 +1,91 @@
+package utils_generics
+
+import (
+	"fmt"
+	"iter"
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestFromSeqChannel(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	result, _ := Collect(done, FromSeqChannel(done, slices.Values([]int{1, 2, 3})))
+	if !IntArrayEquals(result, []int{1, 2, 3}) {
+		t.Fatalf("expected [1 2 3], \n got %v", result)
+	}
+
+	ages := map[string]int{`tom`: 30, `dick`: 40}
+	pairs, _ := Collect(done, FromSeq2Channel(done, maps.All(ages)))
+	if len(pairs) != len(ages) {
+		t.Fatalf("expected %d pairs, got %v", len(ages), pairs)
+	}
+}
+
+func TestFromSeqChannelIsLazy(t *testing.T) {
+	done := make(chan interface{})
+
+	produced := 0
+	stopped := make(chan interface{})
+	naturals := func(yield func(int) bool) {
+		defer close(stopped)
+		for i := 0; ; i++ {
+			produced++
+			if yield(i) == false {
+				return
+			}
+		}
+	}
+
+	valueStream := FromSeqChannel(done, naturals)
+	for i := 0; i < 3; i++ {
+		<-valueStream
+	}
+	close(done)
+	<-stopped // the iterator must be stopped once done is closed.
+
+	fmt.Printf("produced %d\n", produced)
+	if produced > 4 {
+		t.Fatalf("expected to produce at most one value ahead, produced %d", produced)
+	}
+}
+
+func TestToSeq(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	var result []int
+	for v := range ToSeq(done, RangeChannel(done, 0, 5, 1)) {
+		result = append(result, v)
+	}
+	if !IntArrayEquals(result, []int{0, 1, 2, 3, 4}) {
+		t.Fatalf("expected [0 1 2 3 4], \n got %v", result)
+	}
+
+	pairs := FromMapChannel(done, map[string]int{`tom`: 30})
+	for k, v := range ToSeq2(done, pairs) {
+		if k != `tom` || v != 30 {
+			t.Fatalf("expected tom 30, got %s %d", k, v)
+		}
+	}
+}
+
+func TestTakeMapFilterSeq(t *testing.T) {
+	var naturals iter.Seq[int] = func(yield func(int) bool) {
+		for i := 0; yield(i); i++ {
+		}
+	}
+	even := func(v int) bool { return v%2 == 0 }
+	square := func(v int) int { return v * v }
+
+	result := slices.Collect(TakeSeq(MapSeq(FilterSeq(naturals, even), square), 4))
+	if !IntArrayEquals(result, []int{0, 4, 16, 36}) {
+		t.Fatalf("expected [0 4 16 36], \n got %v", result)
+	}
+
+	if result = slices.Collect(TakeSeq(naturals, 0)); len(result) != 0 {
+		t.Fatalf("expected nothing, got %v", result)
+	}
+}
@@ -0,0 +1,69 @@
+package utils_generics
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+)
+
+// Logging the lifecycle of the stages of a Pipeline with log/slog.
+//
+// Logging is off unless a logger is given with WithLogger or StageLogger.
+// Each record carries the pipeline, pipeline_id and stage attributes.
+//
+//	stage started            Info   the stage has been built
+//	stage upstream closed    Info   the stage closed its output, it ran out of input
+//	stage cancelled          Info   the stage stopped because the pipeline was stopped
+//	stage panic recovered    Error  the stage's build func, or fn in a Recover stage, panicked, with panic and stack
+//	stage items dropped      Warn   items the stage produced after it was cancelled, with count
+
+// WithLogger logs the lifecycle of every stage of the Pipeline to logger.
+func WithLogger(logger *slog.Logger) PipelineOption {
+	return func(p *Pipeline) {
+		p.logger = logger
+	}
+}
+
+// StageLogger logs the lifecycle of this stage to logger, instead of the Pipeline's logger.
+func StageLogger(logger *slog.Logger) StageOption {
+	return func(s *stage) {
+		s.logger = logger
+	}
+}
+
+// useLogger picks the stage's logger once its options have been applied.
+func (s *stage) useLogger() {
+	if s.logger == nil {
+		s.logger = s.pipeline.logger
+	}
+	if s.logger != nil {
+		s.logger = s.logger.With(
+			slog.String(pipelineLabel, s.pipeline.name),
+			slog.Uint64(pipelineIDLabel, s.pipeline.id),
+			slog.String(stageLabel, s.name),
+		)
+	}
+}
+
+// log logs msg if the stage has a logger.
+func (s *stage) log(level slog.Level, msg string, attrs ...slog.Attr) {
+	if s.logger == nil {
+		return
+	}
+	s.logger.LogAttrs(context.Background(), level, msg, attrs...)
+}
+
+// runBuild calls build, turning a panic into a failed stage rather than
+// taking the process down with it.
+func (s *stage) runBuild(build func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.failed.Store(true)
+			s.log(slog.LevelError, "stage panic recovered",
+				slog.String("panic", fmt.Sprint(r)),
+				slog.String("stack", string(debug.Stack())))
+		}
+	}()
+	build()
+}
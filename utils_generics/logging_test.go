@@ -0,0 +1,98 @@
+package utils_generics
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPipelineLogging(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+	p := NewPipeline("logged", WithLogger(logger))
+	names := AddStage(p, "generator", func(done <-chan interface{}) <-chan string {
+		return GeneratorToTChannel(done, `tom`, `dick`)
+	})
+	ones := AddStage(p, "repeat", func(done <-chan interface{}) <-chan interface{} {
+		return RepeatValueChannel(done, 1)
+	})
+	broken := AddStage(p, "broken", func(done <-chan interface{}) <-chan int {
+		panic("bad stage")
+	})
+	p.Start()
+
+	Drain(p.Done(), names)
+	if _, ok := <-broken; ok {
+		t.Fatalf("expected the broken stage to close its output")
+	}
+	<-ones
+	p.Stop()
+	p.Wait()
+
+	logs := buf.String()
+	fmt.Print(logs)
+	expected := []string{
+		`msg="stage started" pipeline=logged pipeline_id=`,
+		`msg="stage upstream closed" pipeline=logged pipeline_id=`,
+		`msg="stage cancelled"`,
+		`level=ERROR msg="stage panic recovered"`,
+		`panic="bad stage"`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(logs, line) {
+			t.Fatalf("expected %s in the log", line)
+		}
+	}
+
+	for _, s := range p.Stages() {
+		if s.Name == "broken" && s.State != StageFailed {
+			t.Fatalf("expected the broken stage to have failed, it is %v", s.State)
+		}
+	}
+}
+
+func TestStageLogger(t *testing.T) {
+	var pipelineLog, stageLog bytes.Buffer
+
+	p := NewPipeline("stage logger", WithLogger(slog.New(slog.NewTextHandler(&pipelineLog, nil))))
+	ones := AddStage(p, "repeat", func(done <-chan interface{}) <-chan interface{} {
+		return RepeatValueChannel(done, 1)
+	}, StageLogger(slog.New(slog.NewTextHandler(&stageLog, nil))))
+	buffered := AddStage(p, "buffer", func(done <-chan interface{}) <-chan interface{} {
+		return BufferChannel(done, ones, 4)
+	})
+	p.Start()
+	<-buffered
+	// wait for the buffer to fill up, so there are items to drop.
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if p.Stages()[1].BufferLen > 0 {
+			break
+		}
+	}
+	p.Stop()
+	p.Wait()
+
+	fmt.Print(stageLog.String())
+	if !strings.Contains(stageLog.String(), "stage=repeat") || strings.Contains(pipelineLog.String(), "stage=repeat") {
+		t.Fatalf("expected repeat to log to its own logger")
+	}
+	// the buffer was full when it was cancelled.
+	if !strings.Contains(pipelineLog.String(), `msg="stage items dropped" pipeline="stage logger"`) {
+		t.Fatalf("expected dropped items to be logged, got\n%s", pipelineLog.String())
+	}
+}
+
+func TestPipelineWithoutLoggerDoesNotAllocate(t *testing.T) {
+	s := &stage{name: "quiet", pipeline: NewPipeline("quiet")}
+	s.useLogger()
+	allocs := testing.AllocsPerRun(100, func() {
+		s.log(slog.LevelInfo, "stage started")
+	})
+	if allocs != 0 {
+		t.Fatalf("expected no allocations without a logger, got %v", allocs)
+	}
+}
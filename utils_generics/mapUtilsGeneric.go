@@ -0,0 +1,111 @@
+package utils_generics
+
+import (
+	"sync"
+)
+
+// MapChannel sends fn(v) for every value in the stream, in order.
+func MapChannel[T any, U any, D any](done <-chan D, valueStream <-chan T, fn func(T) U) <-chan U {
+	mapStream := make(chan U)
+	go func() {
+		defer close(mapStream)
+		mapWorker(done, nil, valueStream, mapStream, func(v T) (U, bool) {
+			return fn(v), true
+		})
+	}()
+	return mapStream
+}
+
+// ParallelMapChannel is MapChannel with workers goroutines calling fn at the
+// same time, so the values come out in the order they are finished rather
+// than the order they came in.
+func ParallelMapChannel[T any, U any, D any](done <-chan D, valueStream <-chan T, workers int, fn func(T) U) <-chan U {
+	mapStream := make(chan U)
+	runWorkers(workers, func() {
+		mapWorker(done, nil, valueStream, mapStream, func(v T) (U, bool) {
+			return fn(v), true
+		})
+	}, func() {
+		close(mapStream)
+	})
+	return mapStream
+}
+
+// mapWorker sends the result of apply for each value until the stream is
+// closed, or done or stop is closed.  apply returns false to skip a value.
+func mapWorker[T any, U any, D any](done <-chan D, stop <-chan interface{}, valueStream <-chan T, mapStream chan<- U, apply func(T) (U, bool)) {
+	for {
+		// a stop from the last value wins over taking the next one.
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		select {
+		case <-done:
+			return
+		case <-stop:
+			return
+		case v, ok := <-valueStream:
+			if ok == false {
+				return
+			}
+			u, ok := apply(v)
+			if ok == false {
+				continue
+			}
+			select {
+			case <-done:
+				return
+			case <-stop:
+				return
+			case mapStream <- u:
+			}
+		}
+	}
+}
+
+// runWorkers starts workers goroutines running work, at least one,
+// and calls finished once they have all returned.
+func runWorkers(workers int, work func(), finished func()) {
+	if workers < 1 {
+		workers = 1
+	}
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			work()
+		}()
+	}
+	go func() {
+		wg.Wait()
+		finished()
+	}()
+}
+
+// MapErrChannel is MapChannel for functions that can fail.  The values fn
+// fails on are sent to the dead-letter channel, with stage as their Stage.
+// It is closed along with the values channel.  It must be read, the stage
+// waits for each dead letter to be taken before carrying on.
+func MapErrChannel[T any, U any, D any](done <-chan D, valueStream <-chan T, stage string, fn func(T) (U, error)) (<-chan U, <-chan DeadLetter) {
+	mapStream := make(chan U)
+	deadLetters := make(chan DeadLetter)
+	go func() {
+		defer close(deadLetters)
+		defer close(mapStream)
+		mapWorker(done, nil, valueStream, mapStream, func(v T) (U, bool) {
+			u, err := fn(v)
+			if err == nil {
+				return u, true
+			}
+			select {
+			case <-done:
+			case deadLetters <- newDeadLetter(stage, v, err, 1):
+			}
+			return u, false
+		})
+	}()
+	return mapStream, deadLetters
+}
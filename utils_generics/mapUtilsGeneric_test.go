@@ -0,0 +1,29 @@
+package utils_generics
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestMapChannel(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	square := func(v int) int { return v * v }
+	result, _ := Collect(done, MapChannel(done, RangeChannel(done, 0, 5, 1), square))
+	if !IntArrayEquals(result, []int{0, 1, 4, 9, 16}) {
+		t.Fatalf("expected [0 1 4 9 16], \n got %v", result)
+	}
+}
+
+func TestParallelMapChannel(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	square := func(v int) int { return v * v }
+	result, _ := Collect(done, ParallelMapChannel(done, RangeChannel(done, 0, 5, 1), 3, square))
+	sort.Ints(result)
+	if !IntArrayEquals(result, []int{0, 1, 4, 9, 16}) {
+		t.Fatalf("expected [0 1 4 9 16] in any order, \n got %v", result)
+	}
+}
@@ -0,0 +1,294 @@
+package utils_generics
+
+import (
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics for the stages of a Pipeline created WithMetrics.
+//
+// They are recorded by the goroutine that passes along each stage's output,
+// so "in" is what the stage has produced and "out" is what the next stage has
+// taken.  Time blocked on receive is time spent waiting on the stage (which
+// includes the stage waiting on its own input), time blocked on send is time
+// spent waiting on the next stage.  A stage whose output is always full is
+// waiting on whatever comes after it.
+//
+// The latency of a stage is how long an item took to come through it, from
+// being handed to the stage to coming out of it.  A stage is just channels
+// from the outside, so only items in an Envelope can be followed through it,
+// start a trace with EnvelopeChannel and keep it with Rewrap to time them.
+
+// defaultWaitBuckets are the histogram bucket bounds when WithMetrics is given none.
+var defaultWaitBuckets = []time.Duration{
+	time.Microsecond,
+	10 * time.Microsecond,
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+	10 * time.Second,
+}
+
+// DefaultWaitBuckets returns the upper bounds of the histogram buckets used
+// when WithMetrics is given none.
+func DefaultWaitBuckets() []time.Duration {
+	return slices.Clone(defaultWaitBuckets)
+}
+
+// Histogram is a snapshot of a histogram of durations.
+// Counts[i] is the number of observations <= Bounds[i] and > Bounds[i-1],
+// the last count is for everything over the last bound.
+type Histogram struct {
+	Bounds []time.Duration
+	Counts []uint64
+	Count  uint64
+	Sum    time.Duration
+}
+
+// add adds the counts of other, which has the same bounds, to h.
+func (h *Histogram) add(other *Histogram) {
+	if h.Counts == nil {
+		h.Bounds = other.Bounds
+		h.Counts = make([]uint64, len(other.Counts))
+	}
+	for i := range other.Counts {
+		h.Counts[i] += other.Counts[i]
+	}
+	h.Count += other.Count
+	h.Sum += other.Sum
+}
+
+// Mean returns the average observation, or 0 if there are none.
+func (h Histogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// StageMetrics is a snapshot of the metrics for one stage.  Its
+// OutputMetrics are the totals over its Outputs, most stages have just one.
+type StageMetrics struct {
+	Name       string
+	State      StageState
+	Inputs     []string // stages feeding this one, see Inputs
+	InputPorts []int    // which of the Outputs of each of Inputs feeds this one
+	OutputMetrics
+	Outputs []OutputMetrics // one for each output of the stage, in order
+}
+
+// OutputMetrics is a snapshot of the metrics for one output of a stage.
+type OutputMetrics struct {
+	ItemsIn        uint64        // items received from the stage
+	ItemsOut       uint64        // items taken by the next stage
+	BlockedReceive time.Duration // total time waiting for the stage to produce, so far
+	BlockedSend    time.Duration // total time waiting for the next stage to take, so far
+	BufferLen      int           // items in the stage's output buffer at the last receive
+	BufferCap      int           // capacity of the stage's output buffer
+	MaxBufferLen   int           // most items seen in the stage's output buffer
+	ReceiveWait    Histogram     // time waiting for each item received
+	SendWait       Histogram     // time waiting for each item to be taken
+	Latency        Histogram     // time each Envelope took to come through the stage
+}
+
+// add adds the counts of other, an output of the same stage, to om.
+func (om *OutputMetrics) add(other *OutputMetrics) {
+	om.ItemsIn += other.ItemsIn
+	om.ItemsOut += other.ItemsOut
+	om.BlockedReceive += other.BlockedReceive
+	om.BlockedSend += other.BlockedSend
+	om.BufferLen += other.BufferLen
+	om.BufferCap += other.BufferCap
+	om.MaxBufferLen += other.MaxBufferLen
+	om.ReceiveWait.add(&other.ReceiveWait)
+	om.SendWait.add(&other.SendWait)
+	om.Latency.add(&other.Latency)
+}
+
+// PipelineMetrics is a snapshot of the metrics for every stage in a Pipeline.
+type PipelineMetrics struct {
+	Pipeline   string
+	PipelineID uint64 // see Pipeline.ID, names need not be unique
+	Time       time.Time
+	Stages     []StageMetrics
+}
+
+// stageMetrics are the live counters behind the OutputMetrics of one output.
+// A nil *stageMetrics records nothing, which is what stages get when metrics are off.
+type stageMetrics struct {
+	itemsIn      atomic.Uint64
+	itemsOut     atomic.Uint64
+	bufferLen    atomic.Int64
+	bufferCap    atomic.Int64
+	maxBufferLen atomic.Int64
+	receiveWait  durations
+	sendWait     durations
+	latency      durations
+}
+
+func newStageMetrics(bounds []time.Duration) *stageMetrics {
+	return &stageMetrics{
+		receiveWait: newDurations(bounds),
+		sendWait:    newDurations(bounds),
+		latency:     newDurations(bounds),
+	}
+}
+
+// now returns the time, only if it is going to be used.
+func (m *stageMetrics) now() time.Time {
+	if m == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// received records an item that took waited to arrive, with bufferLen items still in the buffer.
+func (m *stageMetrics) received(waited time.Duration, bufferLen int, bufferCap int) {
+	if m == nil {
+		return
+	}
+	m.itemsIn.Add(1)
+	m.receiveWait.observe(waited)
+	m.bufferLen.Store(int64(bufferLen))
+	m.bufferCap.Store(int64(bufferCap))
+	for {
+		max := m.maxBufferLen.Load()
+		if int64(bufferLen) <= max || m.maxBufferLen.CompareAndSwap(max, int64(bufferLen)) {
+			break
+		}
+	}
+}
+
+// timed records an item that took latency to come through the stage.
+func (m *stageMetrics) timed(latency time.Duration) {
+	if m == nil {
+		return
+	}
+	m.latency.observe(latency)
+}
+
+// sent records an item that took waited to be taken by the next stage.
+func (m *stageMetrics) sent(waited time.Duration) {
+	if m == nil {
+		return
+	}
+	m.itemsOut.Add(1)
+	m.sendWait.observe(waited)
+}
+
+// snapshot copies the counters into sm.
+func (m *stageMetrics) snapshot(sm *OutputMetrics) {
+	if m == nil {
+		return
+	}
+	sm.ItemsIn = m.itemsIn.Load()
+	sm.ItemsOut = m.itemsOut.Load()
+	sm.BufferLen = int(m.bufferLen.Load())
+	sm.BufferCap = int(m.bufferCap.Load())
+	sm.MaxBufferLen = int(m.maxBufferLen.Load())
+	sm.ReceiveWait = m.receiveWait.snapshot()
+	sm.SendWait = m.sendWait.snapshot()
+	sm.Latency = m.latency.snapshot()
+	sm.BlockedReceive = sm.ReceiveWait.Sum
+	sm.BlockedSend = sm.SendWait.Sum
+}
+
+// durations is a live histogram of durations.
+type durations struct {
+	bounds []time.Duration // the Pipeline's buckets, never changed
+	counts []atomic.Uint64
+	sum    atomic.Int64
+}
+
+func newDurations(bounds []time.Duration) durations {
+	return durations{
+		bounds: bounds,
+		counts: make([]atomic.Uint64, len(bounds)+1),
+	}
+}
+
+func (h *durations) observe(d time.Duration) {
+	bucket, _ := slices.BinarySearch(h.bounds, d)
+	h.counts[bucket].Add(1)
+	h.sum.Add(int64(d))
+}
+
+func (h *durations) snapshot() Histogram {
+	snapshot := Histogram{
+		Bounds: slices.Clone(h.bounds),
+		Counts: make([]uint64, len(h.counts)),
+		Sum:    time.Duration(h.sum.Load()),
+	}
+	for i := range h.counts {
+		snapshot.Counts[i] = h.counts[i].Load()
+		snapshot.Count += snapshot.Counts[i]
+	}
+	return snapshot
+}
+
+// waiting adds the wait the port is in the middle of to om.  The counters only
+// record a wait once it is over, without this a stage that is stuck for good
+// would look like it was never blocked at all.
+func (port *stagePort) waiting(om *OutputMetrics, now time.Time) {
+	since := time.Unix(0, port.since.Load())
+	if now.Before(since) {
+		return
+	}
+	switch portState(port.state.Load()) {
+	case portReceiving:
+		om.BlockedReceive += now.Sub(since)
+	case portSending:
+		om.BlockedSend += now.Sub(since)
+	}
+}
+
+// WithMetrics turns on metrics for every stage of the Pipeline, see Snapshot.
+// buckets are the upper bounds of the ReceiveWait, SendWait and Latency
+// histogram buckets, in any order, DefaultWaitBuckets if there are none.
+func WithMetrics(buckets ...time.Duration) PipelineOption {
+	bounds := DefaultWaitBuckets()
+	if len(buckets) > 0 {
+		bounds = slices.Clone(buckets)
+		slices.Sort(bounds)
+		bounds = slices.Compact(bounds)
+	}
+	return func(p *Pipeline) {
+		p.metrics = bounds
+	}
+}
+
+// Snapshot returns the metrics for every stage, in the order they were added.
+// Without WithMetrics only the names, states and inputs are filled in.
+func (p *Pipeline) Snapshot() PipelineMetrics {
+	p.mu.Lock()
+	stages := append([]*stage(nil), p.stages...)
+	p.mu.Unlock()
+
+	pm := PipelineMetrics{
+		Pipeline:   p.name,
+		PipelineID: p.id,
+		Time:       time.Now(),
+		Stages:     make([]StageMetrics, len(stages)),
+	}
+	for i, s := range stages {
+		pm.Stages[i].Name = s.name
+		pm.Stages[i].State = StageState(s.state.Load())
+		for _, input := range s.inputs {
+			pm.Stages[i].Inputs = append(pm.Stages[i].Inputs, input.stage.name)
+			pm.Stages[i].InputPorts = append(pm.Stages[i].InputPorts, input.port)
+		}
+		if p.metrics == nil {
+			continue
+		}
+		pm.Stages[i].Outputs = make([]OutputMetrics, len(s.ports))
+		for j, port := range s.ports {
+			port.metrics.snapshot(&pm.Stages[i].Outputs[j])
+			port.waiting(&pm.Stages[i].Outputs[j], pm.Time)
+			pm.Stages[i].add(&pm.Stages[i].Outputs[j])
+		}
+	}
+	return pm
+}
@@ -0,0 +1,144 @@
+package utils_generics
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPipelineMetrics(t *testing.T) {
+	p := NewPipeline("metrics", WithMetrics())
+
+	generated := AddStage(p, "generator", func(done <-chan interface{}) <-chan int {
+		return RangeChannel(done, 0, 20, 1)
+	})
+	buffered := AddStage(p, "buffer", func(done <-chan interface{}) <-chan int {
+		return BufferTChannel(done, generated, 5)
+	})
+	p.Start()
+
+	// a slow consumer, so the stages spend their time blocked on send.
+	count := 0
+	for range buffered {
+		count++
+		time.Sleep(5 * time.Millisecond)
+	}
+	p.Wait()
+
+	snapshot := p.Snapshot()
+	for _, sm := range snapshot.Stages {
+		fmt.Printf("%s: in %d out %d blocked receive %v send %v buffer %d/%d mean wait %v\n",
+			sm.Name, sm.ItemsIn, sm.ItemsOut, sm.BlockedReceive, sm.BlockedSend,
+			sm.MaxBufferLen, sm.BufferCap, sm.ReceiveWait.Mean())
+		if sm.ItemsIn != 20 || sm.ItemsOut != 20 || sm.ReceiveWait.Count != 20 || sm.SendWait.Count != 20 {
+			t.Fatalf("expected 20 items in and out of %s, got %+v", sm.Name, sm)
+		}
+		if sm.State != StageFinished {
+			t.Fatalf("expected %s to be finished, got %v", sm.Name, sm.State)
+		}
+	}
+
+	buffer := snapshot.Stages[1]
+	if buffer.BufferCap != 5 || buffer.MaxBufferLen == 0 {
+		t.Fatalf("expected the buffer to fill up, got %d/%d", buffer.MaxBufferLen, buffer.BufferCap)
+	}
+	if buffer.BlockedSend < 50*time.Millisecond || buffer.SendWait.Sum != buffer.BlockedSend {
+		t.Fatalf("expected the buffer stage to wait on the slow consumer, waited %v", buffer.BlockedSend)
+	}
+	if buffer.Latency.Count != 0 {
+		t.Fatalf("expected no latency without envelopes, got %+v", buffer.Latency)
+	}
+}
+
+func TestPipelineMetricsLatency(t *testing.T) {
+	p := NewPipeline("latency", WithMetrics())
+	generated := AddStage(p, "generator", func(done <-chan interface{}) <-chan Envelope[int] {
+		return EnvelopeChannel(done, RangeChannel(done, 0, 5, 1))
+	})
+	slow := AddStage(p, "slow", func(done <-chan interface{}) <-chan Envelope[int] {
+		return MapChannel(done, generated, func(e Envelope[int]) Envelope[int] {
+			time.Sleep(10 * time.Millisecond)
+			return Rewrap(e, 2*e.Value)
+		})
+	})
+	p.Start()
+	for range slow {
+	}
+	p.Wait()
+
+	latency := p.Snapshot().Stages[1].Latency
+	fmt.Printf("latency counts %v mean %v\n", latency.Counts, latency.Mean())
+	if latency.Count != 5 || latency.Mean() < 10*time.Millisecond {
+		t.Fatalf("expected 5 items taking at least 10ms each, got %+v", latency)
+	}
+}
+
+func TestPipelineWithoutMetrics(t *testing.T) {
+	p := NewPipeline("no metrics")
+	out := AddStage(p, "generator", func(done <-chan interface{}) <-chan int {
+		return RangeChannel(done, 0, 5, 1)
+	})
+	p.Start()
+	Drain(p.Done(), out)
+	p.Wait()
+
+	snapshot := p.Snapshot()
+	if len(snapshot.Stages) != 1 || snapshot.Stages[0].Name != "generator" || snapshot.Stages[0].ItemsIn != 0 {
+		t.Fatalf("expected just the stage name, got %+v", snapshot.Stages)
+	}
+}
+
+func TestPipelineMetricsBuckets(t *testing.T) {
+	p := NewPipeline("buckets", WithMetrics(time.Hour, time.Nanosecond))
+
+	// changing the defaults does not change a Pipeline's buckets.
+	DefaultWaitBuckets()[0] = time.Minute
+
+	generated := AddStage(p, "generator", func(done <-chan interface{}) <-chan int {
+		return RangeChannel(done, 0, 5, 1)
+	})
+	p.Start()
+	for range generated {
+	}
+	p.Wait()
+
+	wait := p.Snapshot().Stages[0].ReceiveWait
+	fmt.Printf("bounds %v counts %v\n", wait.Bounds, wait.Counts)
+	if len(wait.Bounds) != 2 || wait.Bounds[0] != time.Nanosecond || wait.Bounds[1] != time.Hour {
+		t.Fatalf("expected the bounds sorted, got %v", wait.Bounds)
+	}
+	if len(wait.Counts) != 3 || wait.Count != 5 || wait.Counts[2] != 0 {
+		t.Fatalf("expected 5 items under an hour, got %+v", wait)
+	}
+	if DefaultWaitBuckets()[0] != time.Microsecond {
+		t.Fatalf("expected the default buckets to be unchanged, got %v", DefaultWaitBuckets())
+	}
+}
+
+func TestPipelineMetricsOutputs(t *testing.T) {
+	p := NewPipeline("outputs", WithMetrics())
+	generated := AddStage(p, "generator", func(done <-chan interface{}) <-chan int {
+		return RangeChannel(done, 0, 5, 1)
+	})
+	out1, out2 := AddStage2(p, "tee", func(done <-chan interface{}) (<-chan int, <-chan int) {
+		buffered1, buffered2 := TeeTChannel(done, generated)
+		return BufferTChannel(done, buffered1, 2), buffered2
+	}, Inputs(generated))
+	p.Start()
+	for range out1 {
+		<-out2
+	}
+	p.Wait()
+
+	tee := p.Snapshot().Stages[1]
+	fmt.Printf("%s: %+v\n", tee.Name, tee.Outputs)
+	if len(tee.Outputs) != 2 || tee.Outputs[0].ItemsOut != 5 || tee.Outputs[1].ItemsOut != 5 || tee.ItemsOut != 10 {
+		t.Fatalf("expected 5 items from each output and 10 in all, got %+v", tee)
+	}
+	if tee.Outputs[0].BufferCap != 2 || tee.Outputs[1].BufferCap != 0 {
+		t.Fatalf("expected only the first output to be buffered, got %+v", tee.Outputs)
+	}
+	if tee.Inputs[0] != "generator" || tee.InputPorts[0] != 0 {
+		t.Fatalf("expected tee to be fed by generator, got %v %v", tee.Inputs, tee.InputPorts)
+	}
+}
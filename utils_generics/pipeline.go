@@ -0,0 +1,519 @@
+package utils_generics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/pprof"
+	"runtime/trace"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A Pipeline owns the done channel for a set of named stages, so they can be
+// started together, stopped together and waited on until every goroutine has
+// exited.
+//
+//	p := NewPipeline("primes")
+//	ints := AddStage(p, "generator", func(done <-chan interface{}) <-chan interface{} {
+//		return RepeatFnChannel(done, rand)
+//	})
+//	firstTen := AddStage(p, "take", func(done <-chan interface{}) <-chan interface{} {
+//		return TakeChannel(done, ints, 10)
+//	})
+//	p.Start()
+//	...
+//	p.Stop()
+//	p.Wait()
+//
+// AddStage returns the stage's output straight away so the next stage can be
+// wired to it, but the build func is not called until Start.  The stage's
+// output is passed along by a goroutine owned by the Pipeline, which keeps
+// track of when the stage has finished.
+//
+// Which stages feed which can't be seen from inside the build func, so pass
+// them in with Inputs to record the topology (see WriteDOT):
+//
+//	firstTen := AddStage(p, "take", func(done <-chan interface{}) <-chan interface{} {
+//		return TakeChannel(done, ints, 10)
+//	}, Inputs(ints))
+
+// ErrPipelineStarted is returned by Start if the Pipeline has already been started.
+var ErrPipelineStarted = errors.New("utils_generics: pipeline already started")
+
+// StageState is where a stage is in its lifecycle.
+type StageState int32
+
+const (
+	// StageIdle the stage has been added but the pipeline has not been started.
+	StageIdle StageState = iota
+	// StageRunning the stage has been built and its output is still open.
+	StageRunning
+	// StageFinished the stage closed its output by itself, it ran out of input.
+	StageFinished
+	// StageCancelled the stage stopped because the pipeline was stopped.
+	StageCancelled
+	// StageFailed the stage's build func panicked.
+	StageFailed
+)
+
+func (s StageState) String() string {
+	switch s {
+	case StageIdle:
+		return "idle"
+	case StageRunning:
+		return "running"
+	case StageFinished:
+		return "finished"
+	case StageCancelled:
+		return "cancelled"
+	case StageFailed:
+		return "failed"
+	}
+	return "unknown"
+}
+
+// MarshalText lets StageState show up by name in JSON, such as in expvar.
+func (s StageState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText is the reverse of MarshalText.
+func (s *StageState) UnmarshalText(text []byte) error {
+	for state := StageIdle; state <= StageFailed; state++ {
+		if state.String() == string(text) {
+			*s = state
+			return nil
+		}
+	}
+	return fmt.Errorf("utils_generics: unknown stage state %q", text)
+}
+
+// StageStatus is a snapshot of one stage, as returned by Pipeline.Stages.
+type StageStatus struct {
+	Name       string
+	State      StageState
+	Goroutines int       // goroutines passing along the stage's output, see DebugHandler
+	BufferLen  int       // items waiting in the stage's output buffers
+	BufferCap  int       // capacity of the stage's output buffers
+	LastItem   time.Time // when the next stage last took an item, zero if never
+}
+
+// PipelineOption configures a Pipeline, see NewPipeline.
+type PipelineOption func(p *Pipeline)
+
+// StageOption configures a stage, see AddStage.
+type StageOption func(s *stage)
+
+// Pipeline see above.
+type Pipeline struct {
+	id       uint64
+	name     string
+	done     chan interface{}
+	metrics  []time.Duration // histogram bucket bounds, nil without WithMetrics
+	trace    bool
+	logger   *slog.Logger
+	spans    SpanExporter
+	watchdog *WatchdogConfig
+	stopOn   func()          // waits for WithDone's done, then stops the Pipeline
+	ctx      context.Context // carries the trace task once started
+	task     *trace.Task
+	stopOnce sync.Once
+	finished chan interface{} // closed once every stage has finished after Start
+
+	mu        sync.Mutex
+	started   bool
+	ended     bool // finished has been closed
+	running   int  // stage outputs still open, once started
+	published bool // in expvar, see PublishExpvar
+	stages    []*stage
+	outputs   map[interface{}]stageOutput // stage outputs, to look up Inputs
+}
+
+// stage is one named stage of a Pipeline.
+type stage struct {
+	name     string
+	pipeline *Pipeline
+	start    func()
+	state    atomic.Int32
+	outputs  atomic.Int32 // outputs still open
+	lastItem atomic.Int64 // UnixNano of the last item taken by the next stage
+	ports    []*stagePort // one per output
+	inputs   []stageOutput
+	logger   *slog.Logger
+	failed   atomic.Bool // the build func panicked
+}
+
+// stageOutput is one of the outputs of a stage.
+type stageOutput struct {
+	stage *stage
+	port  int // index into the stage's ports
+}
+
+// NewPipeline creates an empty Pipeline.
+func NewPipeline(name string, opts ...PipelineOption) *Pipeline {
+	p := &Pipeline{
+		id:       pipelineIDs.Add(1),
+		name:     name,
+		done:     make(chan interface{}),
+		finished: make(chan interface{}),
+		outputs:  make(map[interface{}]stageOutput),
+		ctx:      context.Background(),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// pipelineIDs numbers every Pipeline created.
+var pipelineIDs atomic.Uint64
+
+// ID returns a number unique to this Pipeline, as names need not be.
+func (p *Pipeline) ID() uint64 {
+	return p.id
+}
+
+// Name returns the name the Pipeline was created with.
+func (p *Pipeline) Name() string {
+	return p.name
+}
+
+// Done returns the done channel passed to every stage. It is closed by Stop.
+func (p *Pipeline) Done() <-chan interface{} {
+	return p.done
+}
+
+// Start builds every stage in the order they were added.
+// Stages added after Start are built straight away.
+func (p *Pipeline) Start() error {
+	p.mu.Lock()
+	if p.started {
+		p.mu.Unlock()
+		return ErrPipelineStarted
+	}
+	p.started = true
+	if p.trace {
+		p.ctx, p.task = trace.NewTask(p.ctx, "pipeline "+p.name)
+	}
+	stages := append([]*stage(nil), p.stages...)
+	for _, s := range stages {
+		p.running += int(s.outputs.Load())
+	}
+	// every stage is counted before any is started, so finished cannot
+	// close while they are being started.
+	p.running++
+	p.mu.Unlock()
+
+	registerPipeline(p)
+	for _, s := range stages {
+		s.start()
+	}
+	p.outputDone()
+	pprof.Do(p.ctx, p.labels(), func(context.Context) {
+		go func() {
+			<-p.finished
+			unregisterPipeline(p)
+			p.unpublishExpvar()
+			if p.task != nil {
+				p.task.End()
+			}
+		}()
+		if p.watchdog != nil {
+			go p.watch()
+		}
+		if p.stopOn != nil {
+			go p.stopOn()
+		}
+	})
+	return nil
+}
+
+// WithDone stops the Pipeline once done, a done channel of any type such as a
+// context's Done(), is closed or sends.  It is waited on from Start until
+// every stage has finished.
+func WithDone[D any](done <-chan D) PipelineOption {
+	return func(p *Pipeline) {
+		p.stopOn = func() {
+			select {
+			case <-done:
+				p.Stop()
+			case <-p.finished:
+			}
+		}
+	}
+}
+
+// Stop closes the done channel, telling every stage to stop.
+// It is safe to call more than once, and does nothing before Start.
+func (p *Pipeline) Stop() {
+	p.mu.Lock()
+	started := p.started
+	p.mu.Unlock()
+	if started == false {
+		return
+	}
+	p.stopOnce.Do(func() {
+		close(p.done)
+	})
+}
+
+// Wait blocks until every stage has closed its output.
+// It returns straight away for a Pipeline that has not been started.
+func (p *Pipeline) Wait() {
+	p.mu.Lock()
+	started := p.started
+	p.mu.Unlock()
+	if started {
+		<-p.finished
+	}
+}
+
+// outputDone is called as each stage output closes, and by Start once every
+// stage has been started. Once nothing is left open the Pipeline has finished.
+func (p *Pipeline) outputDone() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.running--
+	if p.running == 0 {
+		p.ended = true
+		close(p.finished)
+	}
+}
+
+// Stages returns the state of every stage, in the order they were added.
+func (p *Pipeline) Stages() []StageStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	statuses := make([]StageStatus, 0, len(p.stages))
+	for _, s := range p.stages {
+		status := StageStatus{
+			Name:       s.name,
+			State:      StageState(s.state.Load()),
+			Goroutines: int(s.outputs.Load()),
+		}
+		if status.State == StageIdle {
+			status.Goroutines = 0
+		}
+		for _, port := range s.ports {
+			if port.buffer == nil {
+				continue
+			}
+			length, capacity := port.buffer()
+			status.BufferLen += length
+			status.BufferCap += capacity
+		}
+		if last := s.lastItem.Load(); last != 0 {
+			status.LastItem = time.Unix(0, last)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// Inputs records which stages of the Pipeline feed this one, pass the
+// channels returned by AddStage. Channels from outside the Pipeline are ignored.
+func Inputs(channels ...interface{}) StageOption {
+	return func(s *stage) {
+		for _, c := range channels {
+			if input, ok := s.pipeline.outputs[c]; ok {
+				s.inputs = append(s.inputs, input)
+			}
+		}
+	}
+}
+
+// addStage registers a stage, start is called once the Pipeline is started.
+// outputs are the channels AddStage is returning for this stage.
+// It returns false, without adding the stage, once the Pipeline has finished.
+// It panics if the Pipeline already has a stage called name, the topology
+// and the metrics go by name.
+func (p *Pipeline) addStage(name string, outputs []interface{}, opts []StageOption, start func(s *stage)) bool {
+	s := &stage{name: name, pipeline: p}
+	s.outputs.Store(int32(len(outputs)))
+	s.ports = make([]*stagePort, len(outputs))
+	for i := range s.ports {
+		s.ports[i] = &stagePort{}
+		if p.metrics != nil {
+			s.ports[i].metrics = newStageMetrics(p.metrics)
+		}
+	}
+	s.start = func() {
+		s.state.Store(int32(StageRunning))
+		s.log(slog.LevelInfo, "stage started")
+		// every goroutine started by the stage inherits its labels.
+		pprof.Do(p.ctx, p.labels(stageLabel, name), func(context.Context) {
+			start(s)
+		})
+	}
+
+	p.mu.Lock()
+	for _, other := range p.stages {
+		if other.name == name {
+			p.mu.Unlock()
+			panic(fmt.Sprintf("utils_generics: pipeline %q already has a stage %q", p.name, name))
+		}
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.useLogger()
+	if p.ended {
+		p.mu.Unlock()
+		s.log(slog.LevelWarn, "stage rejected, pipeline finished")
+		return false
+	}
+	for i, c := range outputs {
+		p.outputs[c] = stageOutput{stage: s, port: i}
+	}
+	p.stages = append(p.stages, s)
+	started := p.started
+	if started {
+		p.running += len(outputs)
+	}
+	p.mu.Unlock()
+
+	if started {
+		s.start()
+	}
+	return true
+}
+
+// outputClosed is called as each output of the stage closes,
+// once they are all closed the stage is finished or cancelled.
+func (s *stage) outputClosed() {
+	if s.outputs.Add(-1) > 0 {
+		return
+	}
+	if s.failed.Load() {
+		s.state.Store(int32(StageFailed))
+		return
+	}
+	select {
+	case <-s.pipeline.done:
+		s.state.Store(int32(StageCancelled))
+		s.log(slog.LevelInfo, "stage cancelled")
+	default:
+		s.state.Store(int32(StageFinished))
+		s.log(slog.LevelInfo, "stage upstream closed")
+	}
+}
+
+// AddStage adds a stage with one output to the Pipeline.
+// build is called by Start with the Pipeline's done channel.
+// Each stage needs its own name, AddStage panics on a name already used.
+// Once every stage of the Pipeline has finished, a stage can no longer be
+// added: build is never called and the channel returned is closed.
+func AddStage[T any](p *Pipeline, name string, build func(done <-chan interface{}) <-chan T, opts ...StageOption) <-chan T {
+	out := make(chan T)
+	var output <-chan T = out
+	added := p.addStage(name, []interface{}{output}, opts, func(s *stage) {
+		var in <-chan T
+		s.runBuild(func() {
+			in = build(p.done)
+		})
+		go forwardStage(s, s.ports[0], in, out)
+	})
+	if added == false {
+		close(out)
+	}
+	return output
+}
+
+// AddStage2 adds a stage with two outputs, such as TeeChannel, to the Pipeline.
+func AddStage2[T any, U any](p *Pipeline, name string, build func(done <-chan interface{}) (<-chan T, <-chan U), opts ...StageOption) (<-chan T, <-chan U) {
+	out1 := make(chan T)
+	out2 := make(chan U)
+	var output1 <-chan T = out1
+	var output2 <-chan U = out2
+	added := p.addStage(name, []interface{}{output1, output2}, opts, func(s *stage) {
+		var in1 <-chan T
+		var in2 <-chan U
+		s.runBuild(func() {
+			in1, in2 = build(p.done)
+		})
+		go forwardStage(s, s.ports[0], in1, out1)
+		go forwardStage(s, s.ports[1], in2, out2)
+	})
+	if added == false {
+		close(out1)
+		close(out2)
+	}
+	return output1, output2
+}
+
+// forwardStage passes along the output of a stage until it is closed.
+// Once done is closed it drains what is left, so that by the time it returns
+// the goroutines of the stage have exited. A nil in, from a build func that
+// panicked, just closes out.
+func forwardStage[T any](s *stage, port *stagePort, in <-chan T, out chan<- T) {
+	done := s.pipeline.done
+	m := port.metrics
+	ctx := s.pipeline.ctx
+	s.pipeline.mu.Lock()
+	port.buffer = func() (int, int) { return len(in), cap(in) }
+	s.pipeline.mu.Unlock()
+	defer s.pipeline.outputDone()
+	defer s.outputClosed()
+	defer close(out)
+	if in == nil {
+		return
+	}
+	for {
+		waiting := m.now()
+		endRegion := s.traceRegion(ctx, "receive")
+		port.blocked(portReceiving)
+		select {
+		case <-done:
+			endRegion()
+			port.blocked(portIdle)
+			drainStage(s, in, 0)
+			return
+		case v, ok := <-in:
+			endRegion()
+			port.blocked(portIdle)
+			if ok == false {
+				return
+			}
+			received := m.now()
+			m.received(received.Sub(waiting), len(in), cap(in))
+			v = stageDeadLetter(s, v)
+			v, item, latency := traceItem(s, v, m != nil)
+			if item != nil {
+				m.timed(latency)
+			}
+			endRegion = s.traceRegion(ctx, "send")
+			port.blocked(portSending)
+			select {
+			case out <- v:
+				endRegion()
+				port.blocked(portIdle)
+				m.sent(m.now().Sub(received))
+				now := time.Now()
+				s.lastItem.Store(now.UnixNano())
+				if item != nil {
+					item.handedOff(now)
+				}
+			case <-done:
+				endRegion()
+				port.blocked(portIdle)
+				drainStage(s, in, 1)
+				return
+			}
+		}
+	}
+}
+
+// drainStage reads the stage's output until it closes, once the pipeline has been
+// stopped, and logs how many items were dropped. dropped is any item already
+// received that could not be passed along.
+func drainStage[T any](s *stage, in <-chan T, dropped int) {
+	for range in {
+		dropped++
+	}
+	if dropped > 0 {
+		s.log(slog.LevelWarn, "stage items dropped", slog.Int("count", dropped))
+	}
+}
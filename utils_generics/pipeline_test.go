@@ -0,0 +1,158 @@
+package utils_generics
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPipelineRunsToCompletion(t *testing.T) {
+	p := NewPipeline("names")
+
+	names := []string{`tom`, `dick`, `harry`, `sue`}
+	generated := AddStage(p, "generator", func(done <-chan interface{}) <-chan interface{} {
+		return GeneratorFromStringArrayToChannel(done, names)
+	})
+	buffered := AddStage(p, "buffer", func(done <-chan interface{}) <-chan interface{} {
+		return BufferChannel(done, generated, 2)
+	})
+	out1, out2 := AddStage2(p, "tee", func(done <-chan interface{}) (<-chan interface{}, <-chan interface{}) {
+		return TeeChannel(done, buffered)
+	})
+
+	for _, s := range p.Stages() {
+		if s.State != StageIdle {
+			t.Fatalf("stage %s should be idle before Start, is %v", s.Name, s.State)
+		}
+	}
+
+	if err := p.Start(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := p.Start(); err != ErrPipelineStarted {
+		t.Fatalf("expected %v, got %v", ErrPipelineStarted, err)
+	}
+
+	count := 0
+	for range out1 {
+		<-out2
+		count++
+	}
+	p.Wait()
+
+	if count != len(names) {
+		t.Fatalf("expected %d names, got %d", len(names), count)
+	}
+	for _, s := range p.Stages() {
+		fmt.Printf("%s: %v\n", s.Name, s.State)
+		if s.State != StageFinished {
+			t.Fatalf("stage %s should be finished, is %v", s.Name, s.State)
+		}
+	}
+}
+
+func TestPipelineStop(t *testing.T) {
+	p := NewPipeline("forever")
+
+	ones := AddStage(p, "repeat", func(done <-chan interface{}) <-chan interface{} {
+		return RepeatValueChannel(done, 1)
+	})
+	merged := AddStage(p, "fanIn", func(done <-chan interface{}) <-chan interface{} {
+		return FanInChannel(done, ones, ones)
+	})
+	p.Start()
+
+	for i := 0; i < 10; i++ {
+		<-merged
+	}
+	p.Stop()
+	p.Stop() // safe to call twice
+
+	waited := make(chan interface{})
+	go func() {
+		defer close(waited)
+		p.Wait()
+	}()
+	select {
+	case <-waited:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Wait did not return after Stop")
+	}
+
+	for _, s := range p.Stages() {
+		fmt.Printf("%s: %v\n", s.Name, s.State)
+		if s.State != StageCancelled {
+			t.Fatalf("stage %s should be cancelled, is %v", s.Name, s.State)
+		}
+	}
+}
+
+func TestPipelineNotStarted(t *testing.T) {
+	p := NewPipeline("idle")
+	AddStage(p, "repeat", func(done <-chan interface{}) <-chan interface{} {
+		return RepeatValueChannel(done, 1)
+	})
+
+	waited := make(chan interface{})
+	go func() {
+		defer close(waited)
+		p.Stop()
+		p.Wait()
+	}()
+	select {
+	case <-waited:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Wait did not return for a Pipeline that was never started")
+	}
+	if s := p.Stages()[0]; s.State != StageIdle {
+		t.Fatalf("stage %s should still be idle, is %v", s.Name, s.State)
+	}
+}
+
+func TestPipelineAddStageAfterFinish(t *testing.T) {
+	p := NewPipeline("late")
+	generated := AddStage(p, "generator", func(done <-chan interface{}) <-chan interface{} {
+		return GeneratorToChannel(done, 1, 2)
+	})
+	p.Start()
+	for range generated {
+	}
+	p.Wait()
+
+	built := false
+	late := AddStage(p, "late", func(done <-chan interface{}) <-chan interface{} {
+		built = true
+		return RepeatValueChannel(done, 1)
+	})
+	if _, ok := <-late; ok {
+		t.Fatalf("expected the output of a stage added after finishing to be closed")
+	}
+	if built {
+		t.Fatalf("expected a stage added after finishing not to be built")
+	}
+	if len(p.Stages()) != 1 {
+		t.Fatalf("expected 1 stage, got %d", len(p.Stages()))
+	}
+	p.Wait()
+}
+
+func TestPipelineDuplicateStage(t *testing.T) {
+	p := NewPipeline("twins")
+	ones := AddStage(p, "worker", func(done <-chan interface{}) <-chan interface{} {
+		return RepeatValueChannel(done, 1)
+	})
+
+	defer func() {
+		r := recover()
+		fmt.Println(r)
+		if r == nil {
+			t.Fatalf("expected a second stage called worker to panic")
+		}
+		if len(p.Stages()) != 1 {
+			t.Fatalf("expected 1 stage, got %d", len(p.Stages()))
+		}
+	}()
+	AddStage(p, "worker", func(done <-chan interface{}) <-chan interface{} {
+		return TakeChannel(done, ones, 1)
+	})
+}
@@ -0,0 +1,105 @@
+package utils_generics
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"runtime/pprof"
+	"runtime/trace"
+	"strconv"
+	"strings"
+)
+
+// Telling pipelines apart in profiles and traces.
+//
+// Every goroutine started by a stage of a Pipeline, including the ones
+// started inside OrDoneChannel, FanInChannel and the rest, carries the pprof
+// labels pipeline, pipeline_id and stage.  They show up in goroutine and CPU
+// profiles, e.g.
+//
+//	go tool pprof -tagfocus stage=workers cpu.prof
+//
+// The utilities used on their own can be labelled the same way:
+//
+//	pprof.Do(ctx, pprof.Labels("stage", "workers"), func(context.Context) {
+//		out = FanInChannel(done, workers...)
+//	})
+//
+// WithTrace also wraps the Pipeline in a runtime/trace task, and waiting on
+// each stage for an item or waiting on the next stage to take it in a
+// region, so `go tool trace` shows a timeline per stage.
+
+const (
+	pipelineLabel   = "pipeline"
+	pipelineIDLabel = "pipeline_id"
+	stageLabel      = "stage"
+)
+
+// WithTrace records a runtime/trace task for the Pipeline and a region for
+// each item passed between its stages, while tracing is running.
+func WithTrace() PipelineOption {
+	return func(p *Pipeline) {
+		p.trace = true
+	}
+}
+
+// labels returns the pprof labels for the Pipeline, with any extra label pairs.
+func (p *Pipeline) labels(extra ...string) pprof.LabelSet {
+	return pprof.Labels(append([]string{
+		pipelineLabel, p.name,
+		pipelineIDLabel, strconv.FormatUint(p.id, 10),
+	}, extra...)...)
+}
+
+func endNothing() {}
+
+// traceRegion starts a region named for the stage and what it is waiting on,
+// call the func it returns to end it.
+func (s *stage) traceRegion(ctx context.Context, waitingOn string) func() {
+	if s.pipeline.trace == false || trace.IsEnabled() == false {
+		return endNothing
+	}
+	return trace.StartRegion(ctx, s.name+" "+waitingOn).End
+}
+
+// stageGoroutines counts the goroutines labelled with each stage of each
+// Pipeline, by pipeline id then stage name. It takes a goroutine profile so
+// it is not cheap.
+func stageGoroutines() map[uint64]map[string]int {
+	var buf bytes.Buffer
+	pprof.Lookup("goroutine").WriteTo(&buf, 1)
+
+	// records look like
+	//	3 @ 0x47d82a 0x480985 ...
+	//	# labels: {"pipeline":"primes", "pipeline_id":"1", "stage":"workers"}
+	counts := make(map[uint64]map[string]int)
+	count := 0
+	scanner := bufio.NewScanner(&buf)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if n, _, found := strings.Cut(line, " @ "); found {
+			count, _ = strconv.Atoi(n)
+			continue
+		}
+		labelsJSON, found := strings.CutPrefix(line, "# labels: ")
+		if found == false {
+			continue
+		}
+		var labels map[string]string
+		if json.Unmarshal([]byte(labelsJSON), &labels) != nil {
+			continue
+		}
+		id, err := strconv.ParseUint(labels[pipelineIDLabel], 10, 64)
+		stage, ok := labels[stageLabel]
+		if err != nil || ok == false {
+			continue
+		}
+		if counts[id] == nil {
+			counts[id] = make(map[string]int)
+		}
+		counts[id][stage] += count
+	}
+	return counts
+}
@@ -0,0 +1,60 @@
+package utils_generics
+
+import (
+	"bytes"
+	"fmt"
+	"runtime/trace"
+	"testing"
+)
+
+func TestStageGoroutineLabels(t *testing.T) {
+	p := NewPipeline("labels")
+	ones := AddStage(p, "repeat", func(done <-chan interface{}) <-chan interface{} {
+		return RepeatValueChannel(done, 1)
+	})
+	merged := AddStage(p, "fanIn", func(done <-chan interface{}) <-chan interface{} {
+		return FanInChannel(done, ones, ones, ones)
+	}, Inputs(ones))
+	p.Start()
+	defer func() {
+		p.Stop()
+		p.Wait()
+	}()
+	<-merged
+
+	counts := stageGoroutines()[p.ID()]
+	fmt.Printf("goroutines by stage %v\n", counts)
+	// repeat is its own goroutine and the one passing along its output.
+	if counts["repeat"] != 2 {
+		t.Fatalf("expected 2 goroutines for repeat, got %d", counts["repeat"])
+	}
+	// fanIn has one per channel, one waiting to close the output and the one passing it along.
+	if counts["fanIn"] != 5 {
+		t.Fatalf("expected 5 goroutines for fanIn, got %d", counts["fanIn"])
+	}
+}
+
+func TestPipelineTrace(t *testing.T) {
+	var buf bytes.Buffer
+	if err := trace.Start(&buf); err != nil {
+		t.Skipf("tracing already running: %v", err)
+	}
+
+	p := NewPipeline("traced", WithTrace())
+	out := AddStage(p, "generator", func(done <-chan interface{}) <-chan int {
+		return RangeChannel(done, 0, 10, 1)
+	})
+	p.Start()
+	count, _ := Count(p.Done(), out)
+	p.Wait()
+	trace.Stop()
+
+	if count != 10 {
+		t.Fatalf("expected 10, got %d", count)
+	}
+	for _, name := range []string{"pipeline traced", "generator receive", "generator send"} {
+		if !bytes.Contains(buf.Bytes(), []byte(name)) {
+			t.Fatalf("expected %q in the trace", name)
+		}
+	}
+}
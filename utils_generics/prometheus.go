@@ -0,0 +1,161 @@
+package utils_generics
+
+import (
+	"bufio"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Exposition of pipeline metrics, using nothing but the standard library.
+//
+//	http.Handle("/metrics", MetricsHandler(p1, p2))
+//	p1.PublishExpvar() // shows up under "pipelines" in /debug/vars
+
+// MetricsHandler serves the metrics of the pipelines in the Prometheus text format.
+func MetricsHandler(pipelines ...*Pipeline) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		snapshots := make([]PipelineMetrics, len(pipelines))
+		for i, p := range pipelines {
+			snapshots[i] = p.Snapshot()
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		WritePrometheus(w, snapshots...)
+	})
+}
+
+// promMetric describes one metric family, value pulls it out of a stage's metrics.
+type promMetric struct {
+	name  string
+	kind  string
+	help  string
+	value func(sm *StageMetrics) float64
+}
+
+var promMetrics = []promMetric{
+	{"pipeline_stage_items_in_total", "counter", "Items received from the stage.",
+		func(sm *StageMetrics) float64 { return float64(sm.ItemsIn) }},
+	{"pipeline_stage_items_out_total", "counter", "Items taken by the next stage.",
+		func(sm *StageMetrics) float64 { return float64(sm.ItemsOut) }},
+	{"pipeline_stage_blocked_receive_seconds_total", "counter", "Time spent waiting for the stage to produce.",
+		func(sm *StageMetrics) float64 { return sm.BlockedReceive.Seconds() }},
+	{"pipeline_stage_blocked_send_seconds_total", "counter", "Time spent waiting for the next stage to take.",
+		func(sm *StageMetrics) float64 { return sm.BlockedSend.Seconds() }},
+	{"pipeline_stage_buffer_length", "gauge", "Items in the stage's output buffer.",
+		func(sm *StageMetrics) float64 { return float64(sm.BufferLen) }},
+	{"pipeline_stage_buffer_length_max", "gauge", "Most items seen in the stage's output buffer.",
+		func(sm *StageMetrics) float64 { return float64(sm.MaxBufferLen) }},
+	{"pipeline_stage_buffer_capacity", "gauge", "Capacity of the stage's output buffer.",
+		func(sm *StageMetrics) float64 { return float64(sm.BufferCap) }},
+	{"pipeline_stage_running", "gauge", "1 if the stage is running, otherwise 0.",
+		func(sm *StageMetrics) float64 {
+			if sm.State == StageRunning {
+				return 1
+			}
+			return 0
+		}},
+}
+
+// promHistogram describes one histogram family, value picks it out of a stage's metrics.
+type promHistogram struct {
+	name  string
+	help  string
+	value func(sm *StageMetrics) *Histogram
+}
+
+var promHistograms = []promHistogram{
+	{"pipeline_stage_receive_wait_seconds", "Time waiting for the stage to produce each item.",
+		func(sm *StageMetrics) *Histogram { return &sm.ReceiveWait }},
+	{"pipeline_stage_send_wait_seconds", "Time waiting for the next stage to take each item.",
+		func(sm *StageMetrics) *Histogram { return &sm.SendWait }},
+	{"pipeline_stage_latency_seconds", "Time each traced item took to come through the stage.",
+		func(sm *StageMetrics) *Histogram { return &sm.Latency }},
+}
+
+// WritePrometheus writes the snapshots in the Prometheus text format.
+func WritePrometheus(w io.Writer, snapshots ...PipelineMetrics) error {
+	bw := bufio.NewWriter(w)
+
+	for _, metric := range promMetrics {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", metric.name, metric.help, metric.name, metric.kind)
+		for _, pm := range snapshots {
+			for i := range pm.Stages {
+				sm := &pm.Stages[i]
+				fmt.Fprintf(bw, "%s{%s} %s\n", metric.name, promLabels(&pm, sm.Name), promFloat(metric.value(sm)))
+			}
+		}
+	}
+
+	for _, metric := range promHistograms {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s histogram\n", metric.name, metric.help, metric.name)
+		for _, pm := range snapshots {
+			for i := range pm.Stages {
+				sm := &pm.Stages[i]
+				labels := promLabels(&pm, sm.Name)
+				h := metric.value(sm)
+				var cumulative uint64
+				for b, bound := range h.Bounds {
+					cumulative += h.Counts[b]
+					fmt.Fprintf(bw, "%s_bucket{%s,le=\"%s\"} %d\n", metric.name, labels, promFloat(bound.Seconds()), cumulative)
+				}
+				fmt.Fprintf(bw, "%s_bucket{%s,le=\"+Inf\"} %d\n", metric.name, labels, h.Count)
+				fmt.Fprintf(bw, "%s_sum{%s} %s\n", metric.name, labels, promFloat(h.Sum.Seconds()))
+				fmt.Fprintf(bw, "%s_count{%s} %d\n", metric.name, labels, h.Count)
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+var promEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// promLabels labels a stage's series with the pipeline's id as well as its
+// name, two pipelines with the same name would give duplicate series.
+func promLabels(pm *PipelineMetrics, stage string) string {
+	return `pipeline="` + promEscaper.Replace(pm.Pipeline) + `",pipeline_id="` + strconv.FormatUint(pm.PipelineID, 10) +
+		`",stage="` + promEscaper.Replace(stage) + `"`
+}
+
+func promFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+var (
+	expvarOnce      sync.Once
+	expvarPipelines *expvar.Map
+)
+
+// PublishExpvar publishes the Pipeline's Snapshot under its ID in the
+// expvar map "pipelines", until the Pipeline has finished.
+func (p *Pipeline) PublishExpvar() {
+	expvarOnce.Do(func() {
+		expvarPipelines = expvar.NewMap("pipelines")
+	})
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.ended {
+		return
+	}
+	p.published = true
+	expvarPipelines.Set(p.expvarKey(), expvar.Func(func() any {
+		return p.Snapshot()
+	}))
+}
+
+// unpublishExpvar removes the Pipeline from expvar once it has finished.
+func (p *Pipeline) unpublishExpvar() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.published {
+		expvarPipelines.Delete(p.expvarKey())
+		p.published = false
+	}
+}
+
+func (p *Pipeline) expvarKey() string {
+	return strconv.FormatUint(p.id, 10)
+}
@@ -0,0 +1,108 @@
+package utils_generics
+
+import (
+	"expvar"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	p := NewPipeline(`prom "test"`, WithMetrics())
+	out := AddStage(p, "generator", func(done <-chan interface{}) <-chan int {
+		return RangeChannel(done, 0, 3, 1)
+	})
+	p.Start()
+	Drain(p.Done(), out)
+	p.Wait()
+
+	recorder := httptest.NewRecorder()
+	MetricsHandler(p).ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body := recorder.Body.String()
+	fmt.Print(body)
+
+	labels := fmt.Sprintf(`pipeline="prom \"test\"",pipeline_id="%d",stage="generator"`, p.ID())
+	expected := []string{
+		"# TYPE pipeline_stage_items_in_total counter",
+		`pipeline_stage_items_out_total{` + labels + `} 3`,
+		"# TYPE pipeline_stage_receive_wait_seconds histogram",
+		`pipeline_stage_receive_wait_seconds_bucket{` + labels + `,le="+Inf"} 3`,
+		`pipeline_stage_receive_wait_seconds_count{` + labels + `} 3`,
+		"# TYPE pipeline_stage_send_wait_seconds histogram",
+		`pipeline_stage_send_wait_seconds_count{` + labels + `} 3`,
+		"# TYPE pipeline_stage_latency_seconds histogram",
+		`pipeline_stage_latency_seconds_count{` + labels + `} 0`,
+		`pipeline_stage_running{` + labels + `} 0`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(body, line+"\n") {
+			t.Fatalf("expected the line %s", line)
+		}
+	}
+	if !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("wrong content type %s", recorder.Header().Get("Content-Type"))
+	}
+}
+
+func TestMetricsHandlerSameName(t *testing.T) {
+	var pipelines []*Pipeline
+	for i := 0; i < 2; i++ {
+		p := NewPipeline("twin", WithMetrics())
+		AddStage(p, "generator", func(done <-chan interface{}) <-chan int {
+			return RangeChannel(done, 0, 3, 1)
+		})
+		pipelines = append(pipelines, p)
+	}
+
+	recorder := httptest.NewRecorder()
+	MetricsHandler(pipelines...).ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(recorder.Body.String(), "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		series, _, _ := strings.Cut(line, "} ")
+		if seen[series] {
+			t.Fatalf("duplicate series %s}", series)
+		}
+		seen[series] = true
+	}
+}
+
+func TestPublishExpvar(t *testing.T) {
+	p := NewPipeline("expvar", WithMetrics())
+	out := AddStage(p, "generator", func(done <-chan interface{}) <-chan int {
+		return RangeChannel(done, 0, 3, 1)
+	})
+	p.PublishExpvar()
+	p.PublishExpvar() // publishing again just replaces it
+
+	key := fmt.Sprint(p.ID())
+	vars := expvar.Get("pipelines").(*expvar.Map)
+	published := vars.Get(key)
+	if published == nil {
+		t.Fatalf("expected the pipeline in expvar under %s, got %s", key, vars)
+	}
+	fmt.Println(published)
+	if !strings.Contains(published.String(), `"expvar"`) || !strings.Contains(published.String(), `"State":"idle"`) {
+		t.Fatalf("expected the pipeline's snapshot, got %s", published)
+	}
+
+	// once it has finished it is taken out again.
+	p.Start()
+	Drain(p.Done(), out)
+	p.Wait()
+	deadline := time.Now().Add(5 * time.Second)
+	for vars.Get(key) != nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the finished pipeline to be removed from expvar")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	p.PublishExpvar()
+	if vars.Get(key) != nil {
+		t.Fatalf("expected a finished pipeline not to be published")
+	}
+}
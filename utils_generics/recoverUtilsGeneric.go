@@ -0,0 +1,258 @@
+package utils_generics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stages that call user code can recover from a panic in it, rather than
+// letting it take down the whole process.  The Recover versions of
+// RepeatFnChannel, MapChannel and ParallelMapChannel recover every panic in
+// fn, send it as a *PanicError on the error channel they return, and then do
+// what the Recovery's PanicPolicy says.
+//
+//	values, errs := RecoverMapChannel(done, orders, price, Recovery{
+//		Stage:  "price",
+//		Policy: PanicCancel,
+//		Cancel: p.Stop,
+//	})
+//
+// The error channel is closed along with the values channel.  It must be
+// read, the stage waits for each error to be taken before carrying on.
+//
+// PanicRestart rebuilds whatever state fn keeps before carrying on, fn
+// closes over the state and Restart makes it anew:
+//
+//	var conn *Conn
+//	values, errs := RecoverMapChannel(done, orders, func(o Order) Price {
+//		return conn.Price(o)
+//	}, Recovery{
+//		Policy:  PanicRestart,
+//		Restart: func() { conn = dial() },
+//	})
+
+// PanicPolicy is what a stage does after recovering a panic.
+type PanicPolicy int
+
+const (
+	// PanicContinue drops the value that panicked and carries on.
+	PanicContinue PanicPolicy = iota
+	// PanicBackoff drops the value that panicked and carries on after
+	// Recovery.Backoff, cancelling as PanicCancel would after more than
+	// Recovery.MaxPanics panics.  Nothing is rebuilt, fn is called again
+	// as it is.
+	PanicBackoff
+	// PanicCancel closes the stage's output and calls Recovery.Cancel.
+	PanicCancel
+	// PanicRestart drops the value that panicked and restarts the worker
+	// that panicked: it waits Recovery.Backoff, calls Recovery.Restart to
+	// rebuild fn's state and carries on with the next value.  It cancels as
+	// PanicCancel would after more than Recovery.MaxPanics panics.
+	PanicRestart
+)
+
+func (policy PanicPolicy) String() string {
+	switch policy {
+	case PanicContinue:
+		return "continue"
+	case PanicBackoff:
+		return "backoff"
+	case PanicCancel:
+		return "cancel"
+	case PanicRestart:
+		return "restart"
+	}
+	return fmt.Sprintf("PanicPolicy(%d)", int(policy))
+}
+
+// Recovery configures the Recover stages.
+type Recovery struct {
+	// Stage names the stage in its errors.
+	Stage  string
+	Policy PanicPolicy
+	// Backoff is how long PanicBackoff and PanicRestart wait before carrying on.
+	Backoff time.Duration
+	// MaxPanics is how many panics PanicBackoff and PanicRestart recover
+	// before they cancel as PanicCancel would, 0 for no limit.
+	MaxPanics int
+	// Cancel, if set, is called once when the stage is cancelled by a panic,
+	// for example with a Pipeline's Stop.
+	Cancel func()
+	// Restart, if set, is called by PanicRestart from the worker that
+	// panicked, before it takes the next value.  With several workers it may
+	// be called while the others are still running fn.
+	Restart func()
+	// Logger, if set, logs each panic as the "stage panic recovered" event,
+	// see WithLogger.  Inside a Pipeline pass the same logger as the stage.
+	Logger *slog.Logger
+}
+
+// PanicError is a panic recovered by a stage.
+type PanicError struct {
+	Stage string
+	Value interface{} // the value passed to panic
+	Stack []byte      // the stack of the goroutine that panicked
+}
+
+func (e *PanicError) Error() string {
+	if e.Stage == "" {
+		return fmt.Sprintf("utils_generics: panic: %v", e.Value)
+	}
+	return fmt.Sprintf("utils_generics: stage %s: panic: %v", e.Stage, e.Value)
+}
+
+// Unwrap returns the panic value if it was an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// IsPanic returns true if err is or wraps a *PanicError.
+func IsPanic(err error) bool {
+	var panicErr *PanicError
+	return errors.As(err, &panicErr)
+}
+
+// recoverer applies a Recovery for one stage, which may have several goroutines.
+type recoverer[D any] struct {
+	Recovery
+	done      <-chan D
+	errStream chan error
+	stop      chan interface{} // closed when a panic cancels the stage
+	stopOnce  sync.Once
+	panics    atomic.Int32
+}
+
+func newRecoverer[D any](done <-chan D, recovery Recovery) *recoverer[D] {
+	return &recoverer[D]{
+		Recovery:  recovery,
+		done:      done,
+		errStream: make(chan error),
+		stop:      make(chan interface{}),
+	}
+}
+
+// call runs fn and returns false if it panicked.  The panic has been
+// reported and r.stop closed if the policy says to stop.
+func (r *recoverer[D]) call(fn func()) (ok bool) {
+	defer func() {
+		if v := recover(); v != nil {
+			r.panicked(&PanicError{Stage: r.Stage, Value: v, Stack: debug.Stack()})
+			ok = false
+		}
+	}()
+	fn()
+	return true
+}
+
+func (r *recoverer[D]) panicked(err *PanicError) {
+	if r.Logger != nil {
+		r.Logger.LogAttrs(context.Background(), slog.LevelError, "stage panic recovered",
+			slog.String(stageLabel, r.Stage),
+			slog.String("panic", fmt.Sprint(err.Value)),
+			slog.String("stack", string(err.Stack)))
+	}
+
+	select {
+	case <-r.done:
+		return
+	case <-r.stop:
+		return
+	case r.errStream <- err:
+	}
+
+	switch r.Policy {
+	case PanicContinue:
+		return
+	case PanicBackoff, PanicRestart:
+		panics := int(r.panics.Add(1))
+		if r.MaxPanics > 0 && panics > r.MaxPanics {
+			break
+		}
+		if r.backoff() && r.Policy == PanicRestart && r.Restart != nil {
+			r.Restart()
+		}
+		return
+	}
+
+	r.stopOnce.Do(func() {
+		close(r.stop)
+		if r.Cancel != nil {
+			r.Cancel()
+		}
+	})
+}
+
+// backoff waits for Backoff, it returns false if the stage was stopped meanwhile.
+func (r *recoverer[D]) backoff() bool {
+	if r.Backoff <= 0 {
+		return true
+	}
+	timer := time.NewTimer(r.Backoff)
+	defer timer.Stop()
+	select {
+	case <-r.done:
+		return false
+	case <-r.stop:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// RecoverRepeatFnChannel is RepeatFnChannel for functions that may panic.
+func RecoverRepeatFnChannel[T any, D any](done <-chan D, fn func() T, recovery Recovery) (<-chan T, <-chan error) {
+	r := newRecoverer(done, recovery)
+	valStream := make(chan T)
+	go func() {
+		defer close(r.errStream)
+		defer close(valStream)
+		for {
+			var v T
+			if r.call(func() { v = fn() }) == false {
+				select {
+				case <-done:
+					return
+				case <-r.stop:
+					return
+				default:
+					continue
+				}
+			}
+			select {
+			case <-done:
+				return
+			case valStream <- v:
+			}
+		}
+	}()
+	return valStream, r.errStream
+}
+
+// RecoverMapChannel is MapChannel for functions that may panic.
+func RecoverMapChannel[T any, U any, D any](done <-chan D, valueStream <-chan T, fn func(T) U, recovery Recovery) (<-chan U, <-chan error) {
+	return RecoverParallelMapChannel(done, valueStream, 1, fn, recovery)
+}
+
+// RecoverParallelMapChannel is ParallelMapChannel for functions that may panic.
+// MaxPanics counts the panics of every worker.
+func RecoverParallelMapChannel[T any, U any, D any](done <-chan D, valueStream <-chan T, workers int, fn func(T) U, recovery Recovery) (<-chan U, <-chan error) {
+	r := newRecoverer(done, recovery)
+	mapStream := make(chan U)
+	runWorkers(workers, func() {
+		mapWorker(done, r.stop, valueStream, mapStream, func(v T) (u U, ok bool) {
+			ok = r.call(func() { u = fn(v) })
+			return u, ok
+		})
+	}, func() {
+		close(mapStream)
+		close(r.errStream)
+	})
+	return mapStream, r.errStream
+}
@@ -0,0 +1,186 @@
+package utils_generics
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// panicOnOdd panics on the odd values.
+func panicOnOdd(v int) int {
+	if v%2 == 1 {
+		panic(fmt.Sprintf("odd value %d", v))
+	}
+	return v
+}
+
+// collectBoth reads values and errors until both are closed.
+func collectBoth[T any](values <-chan T, errs <-chan error) ([]T, []error) {
+	var result []T
+	var errList []error
+	for values != nil || errs != nil {
+		select {
+		case v, ok := <-values:
+			if ok == false {
+				values = nil
+				continue
+			}
+			result = append(result, v)
+		case err, ok := <-errs:
+			if ok == false {
+				errs = nil
+				continue
+			}
+			errList = append(errList, err)
+		}
+	}
+	return result, errList
+}
+
+func TestRecoverMapChannelContinue(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	values, errs := RecoverMapChannel(done, RangeChannel(done, 0, 6, 1), panicOnOdd, Recovery{Stage: "evens"})
+	result, errs2 := collectBoth(values, errs)
+	if !IntArrayEquals(result, []int{0, 2, 4}) {
+		t.Fatalf("expected [0 2 4], \n got %v", result)
+	}
+	if len(errs2) != 3 {
+		t.Fatalf("expected 3 errors, got %v", errs2)
+	}
+
+	var panicErr *PanicError
+	if errors.As(errs2[0], &panicErr) == false {
+		t.Fatalf("expected a PanicError, got %T", errs2[0])
+	}
+	fmt.Printf("%v\n", panicErr)
+	if panicErr.Error() != "utils_generics: stage evens: panic: odd value 1" {
+		t.Fatalf("unexpected error %q", panicErr.Error())
+	}
+	if !strings.Contains(string(panicErr.Stack), "panicOnOdd") {
+		t.Fatalf("expected the stack to show panicOnOdd, got %s", panicErr.Stack)
+	}
+}
+
+func TestRecoverMapChannelCancel(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	cancelled := 0
+	values, errs := RecoverMapChannel(done, RangeChannel(done, 0, 6, 1), panicOnOdd, Recovery{
+		Policy: PanicCancel,
+		Cancel: func() { cancelled++ },
+	})
+	result, errs2 := collectBoth(values, errs)
+	if !IntArrayEquals(result, []int{0}) {
+		t.Fatalf("expected [0], \n got %v", result)
+	}
+	if len(errs2) != 1 || !IsPanic(errs2[0]) || cancelled != 1 {
+		t.Fatalf("expected one panic and one cancel, got %v and %d", errs2, cancelled)
+	}
+}
+
+func TestRecoverMapChannelBackoff(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	values, errs := RecoverMapChannel(done, RangeChannel(done, 0, 100, 1), panicOnOdd, Recovery{
+		Policy:    PanicBackoff,
+		Backoff:   time.Millisecond,
+		MaxPanics: 3,
+	})
+	result, errs2 := collectBoth(values, errs)
+	// the fourth panic is one too many.
+	if !IntArrayEquals(result, []int{0, 2, 4, 6}) || len(errs2) != 4 {
+		t.Fatalf("expected [0 2 4 6] and 4 panics, got %v and %d", result, len(errs2))
+	}
+}
+
+func TestRecoverParallelMapChannel(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	values, errs := RecoverParallelMapChannel(done, RangeChannel(done, 0, 100, 1), 4, panicOnOdd, Recovery{})
+	result, errs2 := collectBoth(values, errs)
+	if len(result) != 50 || len(errs2) != 50 {
+		t.Fatalf("expected 50 values and 50 panics, got %d and %d", len(result), len(errs2))
+	}
+}
+
+func TestRecoverRepeatFnChannel(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	calls := 0
+	fn := func() int {
+		calls++
+		return panicOnOdd(calls)
+	}
+	values, errs := RecoverRepeatFnChannel(done, fn, Recovery{Policy: PanicBackoff})
+	for i := 0; i < 3; i++ {
+		if err := <-errs; !IsPanic(err) {
+			t.Fatalf("expected a panic, got %v", err)
+		}
+		if v := <-values; v != 2*(i+1) {
+			t.Fatalf("expected %d, got %d", 2*(i+1), v)
+		}
+	}
+}
+
+func TestRecoverRepeatFnChannelRestart(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	// fn is broken for good once it has panicked, until Restart rebuilds it.
+	var remaining []int
+	restarts := 0
+	fn := func() int {
+		v := remaining[0]
+		remaining = remaining[1:]
+		return v
+	}
+	values, errs := RecoverRepeatFnChannel(done, fn, Recovery{
+		Policy:    PanicRestart,
+		MaxPanics: 2,
+		Restart: func() {
+			restarts++
+			remaining = []int{1, 2}
+		},
+	})
+	result, errs2 := collectBoth(values, errs)
+	// the third panic is one too many.
+	if !IntArrayEquals(result, []int{1, 2, 1, 2}) || len(errs2) != 3 || restarts != 2 {
+		t.Fatalf("expected [1 2 1 2], 3 panics and 2 restarts, \n got %v, %d and %d", result, len(errs2), restarts)
+	}
+}
+
+func TestRecoverLogger(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	var buf bytes.Buffer
+	values, errs := RecoverMapChannel(done, RangeChannel(done, 0, 2, 1), panicOnOdd, Recovery{
+		Stage:  "evens",
+		Logger: slog.New(slog.NewTextHandler(&buf, nil)),
+	})
+	collectBoth(values, errs)
+	log := buf.String()
+	for _, want := range []string{"level=ERROR", `msg="stage panic recovered"`, "stage=evens", `panic="odd value 1"`, "stack="} {
+		if strings.Contains(log, want) == false {
+			t.Fatalf("expected %v in the log, \n got %v", want, log)
+		}
+	}
+}
+
+func TestPanicErrorUnwrap(t *testing.T) {
+	cause := errors.New("bad input")
+	err := error(&PanicError{Value: cause})
+	if errors.Is(err, cause) == false {
+		t.Fatalf("expected the PanicError to wrap %v", cause)
+	}
+}
@@ -0,0 +1,93 @@
+package utils_generics
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrEndOfStream is returned by the fn of a RepeatFnErrChannel when it has
+// nothing more to send, like io.EOF.
+var ErrEndOfStream = errors.New("utils_generics: end of stream")
+
+// RepeatOptions configures RepeatFnErrChannel.  The zero value calls fn as
+// fast as the values are taken and gives up on the first error.
+type RepeatOptions struct {
+	// Retries is how many times a call failing with a transient error is
+	// retried before giving up.
+	Retries int
+	// RetryDelay is how long to wait before each retry.
+	RetryDelay time.Duration
+	// Transient says whether an error is worth retrying, every error is if it is nil.
+	Transient func(error) bool
+	// Interval is the least time between the start of one call and the next.
+	Interval time.Duration
+}
+
+// RepeatFnErrChannel is RepeatFnChannel for sources that can run out or fail.
+// It sends the values fn returns until fn returns ErrEndOfStream, or an error
+// that cannot be retried, or done is closed.  That error, wrapped or not, is
+// sent on the error channel, which is buffered so it can be read once the
+// values channel is closed.  Both channels are closed together.
+func RepeatFnErrChannel[T any, D any](done <-chan D, fn func() (T, error), opts RepeatOptions) (<-chan T, <-chan error) {
+	valStream := make(chan T)
+	errStream := make(chan error, 1)
+	go func() {
+		defer close(errStream)
+		defer close(valStream)
+
+		var last time.Time
+		// wait returns false if done was closed while waiting for d.
+		wait := func(d time.Duration) bool {
+			if d <= 0 {
+				return true
+			}
+			timer := time.NewTimer(d)
+			defer timer.Stop()
+			select {
+			case <-done:
+				return false
+			case <-timer.C:
+				return true
+			}
+		}
+
+		for retries := 0; ; {
+			if opts.Interval > 0 && last.IsZero() == false {
+				if wait(opts.Interval-time.Since(last)) == false {
+					return
+				}
+			}
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			last = time.Now()
+			v, err := fn()
+			if errors.Is(err, ErrEndOfStream) {
+				return
+			}
+			if err != nil {
+				transient := opts.Transient == nil || opts.Transient(err)
+				if transient == false || retries >= opts.Retries {
+					errStream <- err
+					return
+				}
+				retries++
+				if wait(opts.RetryDelay) == false {
+					return
+				}
+				continue
+			}
+			retries = 0
+
+			select {
+			case <-done:
+				return
+			case valStream <- v:
+			}
+		}
+	}()
+	return valStream, errStream
+}
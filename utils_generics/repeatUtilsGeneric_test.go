@@ -0,0 +1,100 @@
+package utils_generics
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// countTo returns a source counting 1 to n.
+func countTo(n int) func() (int, error) {
+	i := 0
+	return func() (int, error) {
+		if i == n {
+			return 0, ErrEndOfStream
+		}
+		i++
+		return i, nil
+	}
+}
+
+func TestRepeatFnErrChannelEndOfStream(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	values, errs := RepeatFnErrChannel(done, countTo(3), RepeatOptions{})
+	result, _ := Collect(done, values)
+	if !IntArrayEquals(result, []int{1, 2, 3}) {
+		t.Fatalf("expected [1 2 3], \n got %v", result)
+	}
+	if err, ok := <-errs; ok {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestRepeatFnErrChannelRetry(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	errFlaky := errors.New("flaky")
+	errBroken := errors.New("broken")
+	calls := 0
+	fn := func() (int, error) {
+		calls++
+		switch calls {
+		case 2, 3:
+			return 0, errFlaky
+		case 5:
+			return 0, errBroken
+		}
+		return calls, nil
+	}
+	opts := RepeatOptions{
+		Retries:    2,
+		RetryDelay: time.Millisecond,
+		Transient:  func(err error) bool { return errors.Is(err, errFlaky) },
+	}
+
+	values, errs := RepeatFnErrChannel(done, fn, opts)
+	result, _ := Collect(done, values)
+	if !IntArrayEquals(result, []int{1, 4}) {
+		t.Fatalf("expected [1 4], \n got %v", result)
+	}
+	if err := <-errs; err != errBroken {
+		t.Fatalf("expected %v, got %v", errBroken, err)
+	}
+
+	// retries run out.
+	calls = 1
+	opts.Retries = 1
+	values, errs = RepeatFnErrChannel(done, fn, opts)
+	if result, _ = Collect(done, values); len(result) != 0 {
+		t.Fatalf("expected nothing, got %v", result)
+	}
+	if err := <-errs; err != errFlaky {
+		t.Fatalf("expected %v, got %v", errFlaky, err)
+	}
+}
+
+func TestRepeatFnErrChannelInterval(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	start := time.Now()
+	values, _ := RepeatFnErrChannel(done, countTo(5), RepeatOptions{Interval: 10 * time.Millisecond})
+	count, _ := Count(done, values)
+	if elapsed := time.Since(start); count != 5 || elapsed < 40*time.Millisecond {
+		t.Fatalf("expected 5 values over at least 40ms, got %d in %v", count, elapsed)
+	}
+}
+
+func TestRepeatFnErrChannelDone(t *testing.T) {
+	done := make(chan interface{})
+	values, errs := RepeatFnErrChannel(done, func() (int, error) { return 1, nil }, RepeatOptions{})
+	<-values
+	close(done)
+	Drain(done, values)
+	if _, ok := <-errs; ok {
+		t.Fatalf("expected the error channel to be closed")
+	}
+}
@@ -0,0 +1,113 @@
+package utils_generics
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff is an exponential backoff with jitter.
+type Backoff struct {
+	// Initial is the delay before the first retry.
+	Initial time.Duration
+	// Max caps the delay, 0 for no cap.
+	Max time.Duration
+	// Multiplier grows the delay after each retry, 2 if it is 0.
+	Multiplier float64
+	// Jitter spreads each delay randomly by up to this fraction either way,
+	// 0.2 makes a 1s delay anything from 0.8s to 1.2s.
+	Jitter float64
+}
+
+// Delay returns how long to wait before retry number retry, counting from 1.
+func (b Backoff) Delay(retry int) time.Duration {
+	multiplier := b.Multiplier
+	if multiplier == 0 {
+		multiplier = 2
+	}
+	delay := float64(b.Initial) * math.Pow(multiplier, float64(retry-1))
+	if b.Max > 0 && delay > float64(b.Max) {
+		delay = float64(b.Max)
+	}
+	if b.Jitter > 0 {
+		delay *= 1 + b.Jitter*(2*rand.Float64()-1)
+	}
+	if delay > math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(delay)
+}
+
+// RetryOptions configures RetryChannel.
+type RetryOptions struct {
+	Backoff
+	// MaxAttempts is how many times fn is called for an item, 3 if it is 0.
+	MaxAttempts int
+	// Retryable says whether an error is worth retrying, every error is if it is nil.
+	Retryable func(error) bool
+}
+
+// RetryChannel sends fn(v) for every value in the stream, in order, calling
+// fn again after a backoff when it fails.  An item that still fails after
+// MaxAttempts, or fails with an error that is not Retryable, is sent to the
+// dead-letter channel instead, with stage as its Stage.  Closing done stops
+// the wait between attempts.
+//
+// The dead-letter channel is closed along with the values channel.  It must
+// be read, the stage waits for each dead letter to be taken before carrying on.
+func RetryChannel[T any, U any, D any](done <-chan D, valueStream <-chan T, stage string, fn func(T) (U, error), opts RetryOptions) (<-chan U, <-chan DeadLetter) {
+	maxAttempts := opts.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 3
+	}
+	retryStream := make(chan U)
+	deadLetters := make(chan DeadLetter)
+	go func() {
+		defer close(deadLetters)
+		defer close(retryStream)
+		for v := range OrDoneTChannel(done, valueStream) {
+			u, letter, ok := retry(done, stage, v, fn, maxAttempts, opts)
+			if ok == false {
+				return
+			}
+			if letter != nil {
+				select {
+				case <-done:
+					return
+				case deadLetters <- *letter:
+				}
+				continue
+			}
+			select {
+			case <-done:
+				return
+			case retryStream <- u:
+			}
+		}
+	}()
+	return retryStream, deadLetters
+}
+
+// retry calls fn for v until it succeeds or gives up, returning a dead letter
+// if it gave up, or false if done was closed.
+func retry[T any, U any, D any](done <-chan D, stage string, v T, fn func(T) (U, error), maxAttempts int, opts RetryOptions) (U, *DeadLetter, bool) {
+	for attempt := 1; ; attempt++ {
+		u, err := fn(v)
+		if err == nil {
+			return u, nil, true
+		}
+		retryable := opts.Retryable == nil || opts.Retryable(err)
+		if retryable == false || attempt >= maxAttempts {
+			letter := newDeadLetter(stage, v, err, attempt)
+			return u, &letter, true
+		}
+
+		timer := time.NewTimer(opts.Delay(attempt))
+		select {
+		case <-done:
+			timer.Stop()
+			return u, nil, false
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package utils_generics
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	b := Backoff{Initial: 10 * time.Millisecond, Max: 50 * time.Millisecond}
+	expected := []time.Duration{10, 20, 40, 50, 50}
+	for i, e := range expected {
+		if d := b.Delay(i + 1); d != e*time.Millisecond {
+			t.Fatalf("retry %d: expected %v, got %v", i+1, e*time.Millisecond, d)
+		}
+	}
+
+	b.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if d := b.Delay(1); d < 5*time.Millisecond || d > 15*time.Millisecond {
+			t.Fatalf("expected 5ms to 15ms, got %v", d)
+		}
+	}
+}
+
+func TestRetryChannel(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	errFlaky := errors.New("flaky")
+	errFatal := errors.New("fatal")
+	calls := make(map[int]int)
+	// 1 works first time, 2 works on the third attempt, 3 always fails and 4 is fatal.
+	fn := func(v int) (string, error) {
+		calls[v]++
+		switch {
+		case v == 2 && calls[v] < 3, v == 3:
+			return "", errFlaky
+		case v == 4:
+			return "", errFatal
+		}
+		return fmt.Sprint(v), nil
+	}
+	opts := RetryOptions{
+		Backoff:   Backoff{Initial: time.Millisecond},
+		Retryable: func(err error) bool { return err != errFatal },
+	}
+
+	values, deadLetters := RetryChannel(done, GeneratorToTChannel(done, 1, 2, 3, 4, 5), "retry", fn, opts)
+	var result []string
+	var letters []DeadLetter
+	for values != nil || deadLetters != nil {
+		select {
+		case v, ok := <-values:
+			if ok == false {
+				values = nil
+				continue
+			}
+			result = append(result, v)
+		case letter, ok := <-deadLetters:
+			if ok == false {
+				deadLetters = nil
+				continue
+			}
+			letters = append(letters, letter)
+		}
+	}
+
+	fmt.Printf("%v %v\n", result, letters)
+	if fmt.Sprint(result) != "[1 2 5]" {
+		t.Fatalf("expected [1 2 5], \n got %v", result)
+	}
+	if len(letters) != 2 {
+		t.Fatalf("expected 2 dead letters, got %v", letters)
+	}
+	if letters[0].Item != 3 || letters[0].Stage != "retry" || letters[0].Err != errFlaky || letters[0].Attempts != 3 {
+		t.Fatalf("expected 3 to fail after 3 attempts, got %+v", letters[0])
+	}
+	if letters[1].Item != 4 || letters[1].Err != errFatal || letters[1].Attempts != 1 {
+		t.Fatalf("expected 4 to fail at once, got %+v", letters[1])
+	}
+}
+
+func TestRetryChannelDone(t *testing.T) {
+	done := make(chan interface{})
+	fail := func(v int) (int, error) { return 0, errors.New("down") }
+	opts := RetryOptions{Backoff: Backoff{Initial: time.Hour}}
+
+	values, deadLetters := RetryChannel(done, GeneratorToTChannel(done, 1), "", fail, opts)
+	time.AfterFunc(10*time.Millisecond, func() { close(done) })
+	select {
+	case <-values:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected closing done to stop the backoff")
+	}
+	if _, ok := <-deadLetters; ok {
+		t.Fatalf("expected no dead letters")
+	}
+}
@@ -0,0 +1,127 @@
+package utils_generics
+
+import (
+	"errors"
+	"time"
+)
+
+// Sinks are the end of a pipeline. They consume a stream until it is closed,
+// the done channel is closed, or the optional timeout expires, which saves
+// writing the same range loop at the bottom of every pipeline.
+//
+// Only the first timeout passed in is used, a timeout <= 0 means wait forever.
+
+// ErrCancelled is returned by a sink when the done channel was closed before
+// the stream it was consuming was closed.
+var ErrCancelled = errors.New("utils_generics: stream cancelled before completion")
+
+// ErrTimeout is returned by a sink when its timeout expired before the stream
+// it was consuming was closed.
+var ErrTimeout = errors.New("utils_generics: timed out waiting for stream")
+
+// ErrEmpty is returned by First and Last when the stream closed without
+// sending anything.
+var ErrEmpty = errors.New("utils_generics: stream closed without any values")
+
+// errStopSink is used internally to stop a ForEach early without an error.
+var errStopSink = errors.New("utils_generics: stop sink")
+
+// sinkTimer returns a channel that fires when the optional timeout expires,
+// along with a func to release the timer.  With no timeout the channel is nil
+// so it never fires in a select.
+func sinkTimer(timeout []time.Duration) (<-chan time.Time, func()) {
+	if len(timeout) == 0 || timeout[0] <= 0 {
+		return nil, func() {}
+	}
+	timer := time.NewTimer(timeout[0])
+	return timer.C, func() { timer.Stop() }
+}
+
+// ForEach calls fn for every value in the stream, in order.
+// It stops at the first error returned by fn and returns that error.
+func ForEach[T any, D any](done <-chan D, valueStream <-chan T, fn func(T) error, timeout ...time.Duration) error {
+	expired, stop := sinkTimer(timeout)
+	defer stop()
+	for {
+		select {
+		case <-done:
+			return ErrCancelled
+		case <-expired:
+			return ErrTimeout
+		case v, ok := <-valueStream:
+			if ok == false {
+				// upstream stages close their streams when done is closed,
+				// so a closed stream does not mean it completed.
+				select {
+				case <-done:
+					return ErrCancelled
+				default:
+					return nil
+				}
+			}
+			if err := fn(v); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Collect gathers every value in the stream into a slice.
+// On error the values collected so far are returned along with the error.
+func Collect[T any, D any](done <-chan D, valueStream <-chan T, timeout ...time.Duration) ([]T, error) {
+	var result []T
+	err := ForEach(done, valueStream, func(v T) error {
+		result = append(result, v)
+		return nil
+	}, timeout...)
+	return result, err
+}
+
+// Count returns the number of values in the stream.
+// On error the count so far is returned along with the error.
+func Count[T any, D any](done <-chan D, valueStream <-chan T, timeout ...time.Duration) (int, error) {
+	count := 0
+	err := ForEach(done, valueStream, func(T) error {
+		count++
+		return nil
+	}, timeout...)
+	return count, err
+}
+
+// First returns the first value in the stream.
+// It does not read the rest of the stream, close done to release the producer.
+func First[T any, D any](done <-chan D, valueStream <-chan T, timeout ...time.Duration) (T, error) {
+	var first T
+	err := ForEach(done, valueStream, func(v T) error {
+		first = v
+		return errStopSink
+	}, timeout...)
+	switch err {
+	case errStopSink:
+		return first, nil
+	case nil:
+		return first, ErrEmpty
+	}
+	return first, err
+}
+
+// Last returns the last value in the stream, reading it until it is closed.
+func Last[T any, D any](done <-chan D, valueStream <-chan T, timeout ...time.Duration) (T, error) {
+	var last T
+	seen := false
+	err := ForEach(done, valueStream, func(v T) error {
+		last = v
+		seen = true
+		return nil
+	}, timeout...)
+	if err == nil && seen == false {
+		err = ErrEmpty
+	}
+	return last, err
+}
+
+// Drain reads and discards the stream until it is closed.
+// Useful to let the upstream stages run to completion.
+func Drain[T any, D any](done <-chan D, valueStream <-chan T, timeout ...time.Duration) error {
+	return ForEach(done, valueStream, func(T) error { return nil }, timeout...)
+}
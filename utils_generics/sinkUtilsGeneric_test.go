@@ -0,0 +1,112 @@
+package utils_generics
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCollect(t *testing.T) {
+	toInt := ToTChannel[int]
+	generator := GeneratorToChannel
+
+	done := make(chan interface{})
+	defer close(done)
+
+	result, err := Collect(done, toInt(done, generator(done, 1, 2, 3, 4)))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expectedResult := []int{1, 2, 3, 4}
+	if !IntArrayEquals(result, expectedResult) {
+		t.Fatalf("expected %v, \n got %v", expectedResult, result)
+	}
+}
+
+func TestForEach(t *testing.T) {
+	toInt := ToTChannel[int]
+	generator := GeneratorToChannel
+
+	done := make(chan interface{})
+	defer close(done)
+
+	sum := 0
+	err := ForEach(done, toInt(done, generator(done, 1, 2, 3)), func(v int) error {
+		sum += v
+		return nil
+	})
+	if err != nil || sum != 6 {
+		t.Fatalf("expected sum 6 and no error, got %d, %v", sum, err)
+	}
+
+	stopErr := errors.New("stop at 2")
+	seen := 0
+	err = ForEach(done, toInt(done, generator(done, 1, 2, 3)), func(v int) error {
+		seen++
+		if v == 2 {
+			return stopErr
+		}
+		return nil
+	})
+	if err != stopErr || seen != 2 {
+		t.Fatalf("expected to stop at 2 with %v, got %d, %v", stopErr, seen, err)
+	}
+}
+
+func TestCountFirstLast(t *testing.T) {
+	toInt := ToTChannel[int]
+	generator := GeneratorToChannel
+
+	done := make(chan interface{})
+	defer close(done)
+
+	count, err := Count(done, toInt(done, generator(done, 5, 6, 7)))
+	if err != nil || count != 3 {
+		t.Fatalf("expected 3, got %d, %v", count, err)
+	}
+
+	first, err := First(done, toInt(done, generator(done, 5, 6, 7)))
+	if err != nil || first != 5 {
+		t.Fatalf("expected 5, got %d, %v", first, err)
+	}
+
+	last, err := Last(done, toInt(done, generator(done, 5, 6, 7)))
+	if err != nil || last != 7 {
+		t.Fatalf("expected 7, got %d, %v", last, err)
+	}
+
+	if _, err = First(done, toInt(done, generator(done))); err != ErrEmpty {
+		t.Fatalf("expected %v, got %v", ErrEmpty, err)
+	}
+	if _, err = Last(done, toInt(done, generator(done))); err != ErrEmpty {
+		t.Fatalf("expected %v, got %v", ErrEmpty, err)
+	}
+}
+
+func TestDrainCancelledAndTimeout(t *testing.T) {
+	repeat := RepeatValueChannel
+	generator := GeneratorToChannel
+
+	done := make(chan interface{})
+
+	if err := Drain(done, generator(done, 1, 2, 3)); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// an infinite stream only stops on the timeout.
+	start := time.Now()
+	count, err := Count(done, repeat(done, 1), 100*time.Millisecond)
+	fmt.Printf("counted %d before timeout after %v\n", count, time.Since(start))
+	if err != ErrTimeout {
+		t.Fatalf("expected %v, got %v", ErrTimeout, err)
+	}
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		close(done)
+	}()
+	if err := Drain(done, repeat(done, 1)); err != ErrCancelled {
+		t.Fatalf("expected %v, got %v", ErrCancelled, err)
+	}
+}
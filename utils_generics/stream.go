@@ -0,0 +1,110 @@
+package utils_generics
+
+import (
+	"iter"
+	"time"
+)
+
+// Stream wraps a channel together with its done channel, so pipelines can be
+// written left to right instead of inside out:
+//
+//	TakeTChannel(done, BufferTChannel(done, in, 4), 10)
+//
+// becomes
+//
+//	NewStream(done, in).Buffer(4).Take(10).Chan()
+//
+// Every method starts the same stage as the function it is named after,
+// so a Stream can be mixed freely with the other utilities via Chan().
+//
+// done can be a channel of any type, such as a context's Done().
+type Stream[T any, D any] struct {
+	done        <-chan D
+	valueStream <-chan T
+}
+
+// NewStream wraps valueStream, every stage added to it stops when done is closed.
+func NewStream[T any, D any](done <-chan D, valueStream <-chan T) Stream[T, D] {
+	return Stream[T, D]{done: done, valueStream: valueStream}
+}
+
+// StreamOf starts a Stream from the values passed in, see GeneratorToTChannel.
+func StreamOf[T any, D any](done <-chan D, values ...T) Stream[T, D] {
+	return NewStream(done, GeneratorToTChannel(done, values...))
+}
+
+// Chan returns the channel at the end of the Stream.
+func (s Stream[T, D]) Chan() <-chan T {
+	return s.valueStream
+}
+
+// Done returns the done channel shared by every stage of the Stream.
+func (s Stream[T, D]) Done() <-chan D {
+	return s.done
+}
+
+// Seq returns an iterator over the Stream, see ToSeq.
+func (s Stream[T, D]) Seq() iter.Seq[T] {
+	return ToSeq(s.done, s.valueStream)
+}
+
+// OrDone see OrDoneTChannel.
+func (s Stream[T, D]) OrDone() Stream[T, D] {
+	return NewStream(s.done, OrDoneTChannel(s.done, s.valueStream))
+}
+
+// Take see TakeTChannel.
+func (s Stream[T, D]) Take(num int) Stream[T, D] {
+	return NewStream(s.done, TakeTChannel(s.done, s.valueStream, num))
+}
+
+// TakeWhile see TakeWhileChannel.
+func (s Stream[T, D]) TakeWhile(pred func(T) bool) Stream[T, D] {
+	return NewStream(s.done, TakeWhileChannel(s.done, s.valueStream, pred))
+}
+
+// Skip see SkipChannel.
+func (s Stream[T, D]) Skip(num int) Stream[T, D] {
+	return NewStream(s.done, SkipChannel(s.done, s.valueStream, num))
+}
+
+// Filter see FilterChannel.
+func (s Stream[T, D]) Filter(pred func(T) bool) Stream[T, D] {
+	return NewStream(s.done, FilterChannel(s.done, s.valueStream, pred))
+}
+
+// Buffer see BufferTChannel.
+func (s Stream[T, D]) Buffer(limit int) Stream[T, D] {
+	return NewStream(s.done, BufferTChannel(s.done, s.valueStream, limit))
+}
+
+// Tee see TeeTChannel. Both Streams have to be read, or neither makes progress.
+func (s Stream[T, D]) Tee() (Stream[T, D], Stream[T, D]) {
+	out1, out2 := TeeTChannel(s.done, s.valueStream)
+	return NewStream(s.done, out1), NewStream(s.done, out2)
+}
+
+// Collect see Collect.
+func (s Stream[T, D]) Collect(timeout ...time.Duration) ([]T, error) {
+	return Collect(s.done, s.valueStream, timeout...)
+}
+
+// ForEach see ForEach.
+func (s Stream[T, D]) ForEach(fn func(T) error, timeout ...time.Duration) error {
+	return ForEach(s.done, s.valueStream, fn, timeout...)
+}
+
+// Count see Count.
+func (s Stream[T, D]) Count(timeout ...time.Duration) (int, error) {
+	return Count(s.done, s.valueStream, timeout...)
+}
+
+// First see First.
+func (s Stream[T, D]) First(timeout ...time.Duration) (T, error) {
+	return First(s.done, s.valueStream, timeout...)
+}
+
+// Drain see Drain.
+func (s Stream[T, D]) Drain(timeout ...time.Duration) error {
+	return Drain(s.done, s.valueStream, timeout...)
+}
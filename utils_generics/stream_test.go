@@ -0,0 +1,98 @@
+package utils_generics
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestStream(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	even := func(v int) bool { return v%2 == 0 }
+	result, err := NewStream(done, IterateChannel(done, 0, func(v int) int { return v + 1 })).
+		Filter(even).
+		Buffer(4).
+		Take(5).
+		Collect()
+	if err != nil || !IntArrayEquals(result, []int{0, 2, 4, 6, 8}) {
+		t.Fatalf("expected [0 2 4 6 8], \n got %v, %v", result, err)
+	}
+}
+
+func TestStreamContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := NewStream(ctx.Done(), RepeatValueTChannel(ctx.Done(), 1)).Buffer(2)
+	if v, err := s.First(); err != nil || v != 1 {
+		t.Fatalf("expected 1, \n got %v, %v", v, err)
+	}
+	cancel()
+	for range s.Chan() {
+	}
+	if s.Done() != ctx.Done() {
+		t.Fatalf("expected the context's done channel")
+	}
+}
+
+func TestStreamTee(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	out1, out2 := StreamOf(done, 1, 2, 3).Tee()
+
+	var wg sync.WaitGroup
+	var result1, result2 []int
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		result1, _ = out1.Collect()
+	}()
+	go func() {
+		defer wg.Done()
+		result2, _ = out2.Collect()
+	}()
+	wg.Wait()
+
+	fmt.Printf("Out1: %v, out2: %v\n", result1, result2)
+	if !IntArrayEquals(result1, []int{1, 2, 3}) || !IntArrayEquals(result1, result2) {
+		t.Fatalf("expected [1 2 3] from both, got %v and %v", result1, result2)
+	}
+}
+
+func TestStreamInterop(t *testing.T) {
+	toInt := ToTChannel[int]
+	repeat := RepeatValueChannel
+
+	done := make(chan interface{})
+	defer close(done)
+
+	// raw channels in, raw channel out.
+	s := NewStream(done, toInt(done, repeat(done, 1, 2))).Take(4)
+	total := 0
+	for v := range s.Chan() {
+		total += v
+	}
+	if total != 6 {
+		t.Fatalf("expected 6, got %d", total)
+	}
+
+	count := 0
+	for range StreamOf(done, `a`, `b`).Seq() {
+		count++
+	}
+	if count != 2 {
+		t.Fatalf("expected 2, got %d", count)
+	}
+}
+
+func TestTakeTChannelUpstreamCloses(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	result, _ := Collect(done, TakeTChannel(done, GeneratorToTChannel(done, 1, 2), 5))
+	if !IntArrayEquals(result, []int{1, 2}) {
+		t.Fatalf("expected [1 2], \n got %v", result)
+	}
+}
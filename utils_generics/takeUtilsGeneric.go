@@ -0,0 +1,145 @@
+package utils_generics
+
+import (
+	"time"
+)
+
+// Siblings of TakeChannel. Each of these closes the channel it returns when
+// the done channel is closed, when it has finished taking, or when the
+// incoming stream is closed, whichever comes first.
+
+// TakeWhileChannel passes along values while pred returns true.
+// The first value that fails pred is dropped and the stream is closed.
+func TakeWhileChannel[T any, D any](done <-chan D, valueStream <-chan T, pred func(T) bool) <-chan T {
+	takeStream := make(chan T)
+	go func() {
+		defer close(takeStream)
+		for {
+			select {
+			case <-done:
+				return
+			case v, ok := <-valueStream:
+				if ok == false || pred(v) == false {
+					return
+				}
+				select {
+				case <-done:
+					return
+				case takeStream <- v:
+				}
+			}
+		}
+	}()
+	return takeStream
+}
+
+// FilterChannel passes along only the values for which pred returns true.
+func FilterChannel[T any, D any](done <-chan D, valueStream <-chan T, pred func(T) bool) <-chan T {
+	filterStream := make(chan T)
+	go func() {
+		defer close(filterStream)
+		for {
+			select {
+			case <-done:
+				return
+			case v, ok := <-valueStream:
+				if ok == false {
+					return
+				}
+				if pred(v) == false {
+					continue
+				}
+				select {
+				case <-done:
+					return
+				case filterStream <- v:
+				}
+			}
+		}
+	}()
+	return filterStream
+}
+
+// SkipWhileChannel drops values while pred returns true, then passes along
+// the first value that fails pred and everything after it.
+func SkipWhileChannel[T any, D any](done <-chan D, valueStream <-chan T, pred func(T) bool) <-chan T {
+	skipStream := make(chan T)
+	go func() {
+		defer close(skipStream)
+		skipping := true
+		for {
+			select {
+			case <-done:
+				return
+			case v, ok := <-valueStream:
+				if ok == false {
+					return
+				}
+				if skipping && pred(v) {
+					continue
+				}
+				skipping = false
+				select {
+				case <-done:
+					return
+				case skipStream <- v:
+				}
+			}
+		}
+	}()
+	return skipStream
+}
+
+// SkipChannel drops the first num items from the incoming stream and passes along the rest.
+// Handy for skipping a header.
+func SkipChannel[T any, D any](done <-chan D, valueStream <-chan T, num int) <-chan T {
+	skipped := 0
+	return SkipWhileChannel(done, valueStream, func(T) bool {
+		skipped++
+		return skipped <= num
+	})
+}
+
+// TakeUntilChannel passes along values until the signal channel, of any
+// type, is closed or sends a value.
+func TakeUntilChannel[T any, D any, S any](done <-chan D, valueStream <-chan T, signal <-chan S) <-chan T {
+	takeStream := make(chan T)
+	go func() {
+		defer close(takeStream)
+		for {
+			select {
+			case <-done:
+				return
+			case <-signal:
+				return
+			case v, ok := <-valueStream:
+				if ok == false {
+					return
+				}
+				select {
+				case <-done:
+					return
+				case <-signal:
+					return
+				case takeStream <- v:
+				}
+			}
+		}
+	}()
+	return takeStream
+}
+
+// TakeForChannel passes along values for duration d, starting from when it is called.
+func TakeForChannel[T any, D any](done <-chan D, valueStream <-chan T, d time.Duration) <-chan T {
+	timer := time.NewTimer(d)
+	expired := make(chan interface{})
+	go func() {
+		defer timer.Stop()
+		select {
+		case <-done:
+		case <-timer.C:
+			close(expired)
+		}
+	}()
+	return TakeUntilChannel(done, valueStream, expired)
+}
@@ -0,0 +1,82 @@
+package utils_generics
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestTakeWhileChannel(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	lessThan4 := func(v int) bool { return v < 4 }
+	result, _ := Collect(done, TakeWhileChannel(done, RangeChannel(done, 0, 100, 1), lessThan4))
+	if !IntArrayEquals(result, []int{0, 1, 2, 3}) {
+		t.Fatalf("expected [0 1 2 3], \n got %v", result)
+	}
+
+	// upstream closes before pred fails.
+	result, _ = Collect(done, TakeWhileChannel(done, RangeChannel(done, 0, 2, 1), lessThan4))
+	if !IntArrayEquals(result, []int{0, 1}) {
+		t.Fatalf("expected [0 1], \n got %v", result)
+	}
+}
+
+func TestSkipWhileAndSkipChannel(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	lessThan4 := func(v int) bool { return v < 4 }
+	result, _ := Collect(done, SkipWhileChannel(done, GeneratorToTChannel(done, 1, 5, 2, 6), lessThan4))
+	if !IntArrayEquals(result, []int{5, 2, 6}) {
+		t.Fatalf("expected [5 2 6], \n got %v", result)
+	}
+
+	lines := []string{`name,age`, `tom,30`, `dick,40`}
+	rows, _ := Collect(done, SkipChannel(done, GeneratorToTChannel(done, lines...), 1))
+	fmt.Printf("%v\n", rows)
+	if len(rows) != 2 || rows[0] != `tom,30` {
+		t.Fatalf("expected the header to be skipped, got %v", rows)
+	}
+
+	// upstream closes before the skip is finished.
+	if rows, _ = Collect(done, SkipChannel(done, GeneratorToTChannel(done, lines...), 10)); len(rows) != 0 {
+		t.Fatalf("expected nothing, got %v", rows)
+	}
+}
+
+func TestTakeUntilChannel(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	signal := make(chan interface{})
+	values := IterateChannel(done, 0, func(v int) int { return v + 1 })
+	taken := TakeUntilChannel(done, values, signal)
+
+	for i := 0; i < 3; i++ {
+		<-taken
+	}
+	close(signal)
+
+	count, err := Count(done, taken, time.Second)
+	if err != nil || count > 1 {
+		t.Fatalf("expected the stream to close on the signal, got %d more, %v", count, err)
+	}
+}
+
+func TestTakeForChannel(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	repeat := RepeatValueChannel
+	toInt := ToTChannel[int]
+
+	start := time.Now()
+	count, err := Count(done, TakeForChannel(done, toInt(done, repeat(done, 1)), 100*time.Millisecond), time.Second)
+	elapsed := time.Since(start)
+	fmt.Printf("took %d values in %v\n", count, elapsed)
+	if err != nil || elapsed < 100*time.Millisecond || elapsed > 500*time.Millisecond {
+		t.Fatalf("expected to stop after 100ms, stopped after %v, %v", elapsed, err)
+	}
+}
@@ -0,0 +1,342 @@
+package utils_generics
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"maps"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Following single items through a Pipeline.
+//
+// Wrap items in an Envelope and run them through a Pipeline created
+// WithSpanExporter.  Every stage the envelope comes out of records a span,
+// from when the stage before handed it on until this stage produced it, as a
+// child of the span from the stage before.  The stages themselves just pass
+// the Envelope along like any other value, so OrDoneChannel, BufferChannel,
+// FanInChannel and the rest need no changes.
+//
+//	exporter, _ := NewFileSpanExporter("spans.jsonl")
+//	defer exporter.Close()
+//	p := NewPipeline("orders", WithSpanExporter(exporter))
+//	orders := AddStage(p, "source", func(done <-chan interface{}) <-chan Envelope[Order] {
+//		return EnvelopeChannel(done, readOrders(done))
+//	})
+//
+// An Envelope that goes through TeeChannel keeps its trace id, each copy's
+// spans carrying on from the stage before the tee.
+
+// TraceID identifies every span of one item, as in OpenTelemetry.
+type TraceID [16]byte
+
+// SpanID identifies one span, as in OpenTelemetry.
+type SpanID [8]byte
+
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+func (s SpanID) String() string  { return hex.EncodeToString(s[:]) }
+
+// IsZero is true for the SpanID of an item that has not been through a stage yet.
+func (s SpanID) IsZero() bool { return s == SpanID{} }
+
+// Span is the time one item spent in one stage.
+type Span struct {
+	TraceID      TraceID
+	SpanID       SpanID
+	ParentSpanID SpanID
+	Name         string
+	Start        time.Time
+	End          time.Time
+	Attributes   map[string]string
+}
+
+// SpanExporter receives each Span as a stage finishes with an item.
+// It is called from the Pipeline's goroutines, so it must be safe for concurrent use.
+type SpanExporter interface {
+	ExportSpan(span Span)
+}
+
+// WithSpanExporter records a Span for each Envelope coming out of each stage.
+func WithSpanExporter(exporter SpanExporter) PipelineOption {
+	return func(p *Pipeline) {
+		p.spans = exporter
+	}
+}
+
+// Envelope carries a value along with its trace.
+type Envelope[T any] struct {
+	Value T
+	trace *itemTrace
+}
+
+// itemTrace is where an Envelope is in its trace.  Each stage it comes out of
+// gives it a new one, so copies made by a stage, such as TeeChannel, each go
+// on from the span they share without mixing up the spans after it.
+type itemTrace struct {
+	mu      sync.Mutex
+	traceID TraceID
+	parent  SpanID    // the span of the last stage it came out of
+	handoff time.Time // when the last stage handed it on
+}
+
+// traced is implemented by every Envelope, whatever it carries.
+type traced interface {
+	itemTrace() *itemTrace
+	withTrace(t *itemTrace) any
+}
+
+func (e Envelope[T]) itemTrace() *itemTrace {
+	return e.trace
+}
+
+func (e Envelope[T]) withTrace(t *itemTrace) any {
+	return Envelope[T]{Value: e.Value, trace: t}
+}
+
+// NewEnvelope starts a new trace for v.
+func NewEnvelope[T any](v T) Envelope[T] {
+	t := &itemTrace{handoff: time.Now()}
+	putRandom(t.traceID[:])
+	return Envelope[T]{Value: v, trace: t}
+}
+
+// Rewrap puts v in an Envelope carrying the same trace as e,
+// for stages that turn one value into another.
+func Rewrap[T any, U any](e Envelope[T], v U) Envelope[U] {
+	return Envelope[U]{Value: v, trace: e.trace}
+}
+
+// TraceID returns the id of the Envelope's trace.
+func (e Envelope[T]) TraceID() TraceID {
+	if e.trace == nil {
+		return TraceID{}
+	}
+	e.trace.mu.Lock()
+	defer e.trace.mu.Unlock()
+	return e.trace.traceID
+}
+
+// SpanID returns the id of the span of the last stage the Envelope came out of.
+func (e Envelope[T]) SpanID() SpanID {
+	if e.trace == nil {
+		return SpanID{}
+	}
+	e.trace.mu.Lock()
+	defer e.trace.mu.Unlock()
+	return e.trace.parent
+}
+
+// EnvelopeChannel starts a trace for every value in the stream.
+func EnvelopeChannel[T any, D any](done <-chan D, valueStream <-chan T) <-chan Envelope[T] {
+	envelopeStream := make(chan Envelope[T])
+	go func() {
+		defer close(envelopeStream)
+		for v := range OrDoneTChannel(done, valueStream) {
+			select {
+			case <-done:
+				return
+			case envelopeStream <- NewEnvelope(v):
+			}
+		}
+	}()
+	return envelopeStream
+}
+
+// UnwrapChannel takes the values back out of their envelopes.
+func UnwrapChannel[T any, D any](done <-chan D, envelopeStream <-chan Envelope[T]) <-chan T {
+	valueStream := make(chan T)
+	go func() {
+		defer close(valueStream)
+		for e := range OrDoneTChannel(done, envelopeStream) {
+			select {
+			case <-done:
+				return
+			case valueStream <- e.Value:
+			}
+		}
+	}()
+	return valueStream
+}
+
+// stageSpan ends the span for the stage the item has just come out of,
+// and returns the trace the item carries on with.
+func (t *itemTrace) stageSpan(s *stage, end time.Time) (Span, *itemTrace) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	span := Span{
+		TraceID:      t.traceID,
+		ParentSpanID: t.parent,
+		Name:         s.name,
+		Start:        t.handoff,
+		End:          end,
+		Attributes: map[string]string{
+			pipelineLabel:   s.pipeline.name,
+			pipelineIDLabel: strconv.FormatUint(s.pipeline.id, 10),
+			stageLabel:      s.name,
+		},
+	}
+	putRandom(span.SpanID[:])
+	return span, &itemTrace{traceID: t.traceID, parent: span.SpanID, handoff: end}
+}
+
+// handedOff records when the item was taken by the next stage.
+func (t *itemTrace) handedOff(when time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.handoff = when
+}
+
+// traceItem ends the span for v, which has just come out of the stage, if it
+// is an Envelope and the Pipeline is exporting spans or timing stages.
+// It returns v carrying its new trace, the trace so the handoff can be
+// recorded, or nil, and how long v took to come through the stage.
+func traceItem[T any](s *stage, v T, timed bool) (T, *itemTrace, time.Duration) {
+	exporter := s.pipeline.spans
+	if exporter == nil && timed == false {
+		return v, nil, 0
+	}
+	e, ok := any(v).(traced)
+	if ok == false || e.itemTrace() == nil {
+		return v, nil, 0
+	}
+	span, t := e.itemTrace().stageSpan(s, time.Now())
+	if exporter != nil {
+		exporter.ExportSpan(span)
+	}
+	return e.withTrace(t).(T), t, span.End.Sub(span.Start)
+}
+
+func putRandom(b []byte) {
+	for i := 0; i < len(b); i += 8 {
+		r := rand.Uint64()
+		for j := i; j < len(b) && j < i+8; j++ {
+			b[j] = byte(r)
+			r >>= 8
+		}
+	}
+}
+
+// FileSpanExporter writes spans to a file as OpenTelemetry (OTLP) JSON,
+// one TracesData object per line, the same as the OpenTelemetry Collector's
+// file exporter, so they can be loaded into trace viewers offline.
+type FileSpanExporter struct {
+	mu   sync.Mutex
+	file *os.File
+	w    *bufio.Writer
+	err  error
+}
+
+// NewFileSpanExporter creates the file at path, truncating it if it exists.
+func NewFileSpanExporter(path string) (*FileSpanExporter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSpanExporter{file: f, w: bufio.NewWriter(f)}, nil
+}
+
+// ExportSpan writes the span. Errors are kept and returned by Close.
+func (e *FileSpanExporter) ExportSpan(span Span) {
+	line, err := json.Marshal(otlpTraces(span))
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.err != nil || e.file == nil {
+		return
+	}
+	if err == nil {
+		_, err = e.w.Write(append(line, '\n'))
+	}
+	e.err = err
+}
+
+// Close flushes the file and closes it, returning the first error since it was created.
+func (e *FileSpanExporter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.file == nil {
+		return e.err
+	}
+	if err := e.w.Flush(); e.err == nil {
+		e.err = err
+	}
+	if err := e.file.Close(); e.err == nil {
+		e.err = err
+	}
+	e.file = nil
+	return e.err
+}
+
+// The OTLP JSON encoding, just the parts needed for spans.
+type otlpKeyValue struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpTracesData struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+// otlpSpanKindInternal is SPAN_KIND_INTERNAL.
+const otlpSpanKindInternal = 1
+
+// otlpString is a string attribute.
+func otlpString(key string, value string) otlpKeyValue {
+	kv := otlpKeyValue{Key: key}
+	kv.Value.StringValue = value
+	return kv
+}
+
+func otlpTraces(span Span) otlpTracesData {
+	exported := otlpSpan{
+		TraceID:           span.TraceID.String(),
+		SpanID:            span.SpanID.String(),
+		Name:              span.Name,
+		Kind:              otlpSpanKindInternal,
+		StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+	}
+	if span.ParentSpanID.IsZero() == false {
+		exported.ParentSpanID = span.ParentSpanID.String()
+	}
+	for _, key := range slices.Sorted(maps.Keys(span.Attributes)) {
+		exported.Attributes = append(exported.Attributes, otlpString(key, span.Attributes[key]))
+	}
+
+	scope := otlpScopeSpans{Spans: []otlpSpan{exported}}
+	scope.Scope.Name = "utils_generics"
+	resource := otlpResourceSpans{ScopeSpans: []otlpScopeSpans{scope}}
+	resource.Resource.Attributes = []otlpKeyValue{otlpString("service.name", "utils_generics")}
+	return otlpTracesData{ResourceSpans: []otlpResourceSpans{resource}}
+}
@@ -0,0 +1,177 @@
+package utils_generics
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// spanRecorder keeps the spans in memory.
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []Span
+}
+
+func (r *spanRecorder) ExportSpan(span Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, span)
+}
+
+func TestEnvelopeSpans(t *testing.T) {
+	recorder := &spanRecorder{}
+	p := NewPipeline("traced", WithSpanExporter(recorder))
+
+	names := []string{`tom`, `dick`, `harry`}
+	source := AddStage(p, "source", func(done <-chan interface{}) <-chan Envelope[string] {
+		return EnvelopeChannel(done, GeneratorToTChannel(done, names...))
+	})
+	// through the interface{} stages and back.
+	orDone := AddStage(p, "orDone", func(done <-chan interface{}) <-chan interface{} {
+		asInterface := make(chan interface{})
+		go func() {
+			defer close(asInterface)
+			for e := range source {
+				asInterface <- e
+			}
+		}()
+		return OrDoneChannel(done, asInterface)
+	}, Inputs(source))
+	buffered := AddStage(p, "buffer", func(done <-chan interface{}) <-chan interface{} {
+		return BufferChannel(done, orDone, 2)
+	}, Inputs(orDone))
+	merged := AddStage(p, "fanIn", func(done <-chan interface{}) <-chan interface{} {
+		return FanInChannel(done, buffered)
+	}, Inputs(buffered))
+	p.Start()
+
+	var envelopes []Envelope[string]
+	for v := range merged {
+		envelopes = append(envelopes, v.(Envelope[string]))
+	}
+	p.Wait()
+
+	if len(envelopes) != len(names) || len(recorder.spans) != 4*len(names) {
+		t.Fatalf("expected %d envelopes with 4 spans each, got %d and %d spans", len(names), len(envelopes), len(recorder.spans))
+	}
+
+	// walk each item's spans back from the last stage to the first.
+	byID := make(map[SpanID]Span)
+	for _, span := range recorder.spans {
+		byID[span.SpanID] = span
+	}
+	for _, e := range envelopes {
+		var path []string
+		for id := e.SpanID(); id.IsZero() == false; id = byID[id].ParentSpanID {
+			span := byID[id]
+			if span.TraceID != e.TraceID() {
+				t.Fatalf("span %v is from trace %v, not %v", id, span.TraceID, e.TraceID())
+			}
+			if span.End.Before(span.Start) {
+				t.Fatalf("span %s ends before it starts", span.Name)
+			}
+			path = append([]string{span.Name}, path...)
+		}
+		t.Logf("%s: %v", e.Value, path)
+		if len(path) != 4 || path[0] != "source" || path[3] != "fanIn" {
+			t.Fatalf("expected source, orDone, buffer, fanIn, got %v", path)
+		}
+	}
+}
+
+func TestEnvelopeSpansTee(t *testing.T) {
+	recorder := &spanRecorder{}
+	p := NewPipeline("traced tee", WithSpanExporter(recorder))
+
+	source := AddStage(p, "source", func(done <-chan interface{}) <-chan Envelope[int] {
+		return EnvelopeChannel(done, GeneratorToTChannel(done, 1, 2, 3))
+	})
+	tee1, tee2 := AddStage2(p, "tee", func(done <-chan interface{}) (<-chan Envelope[int], <-chan Envelope[int]) {
+		return TeeTChannel(done, source)
+	}, Inputs(source))
+	left := AddStage(p, "left", func(done <-chan interface{}) <-chan Envelope[int] {
+		return OrDoneTChannel(done, tee1)
+	}, Inputs(tee1))
+	right := AddStage(p, "right", func(done <-chan interface{}) <-chan Envelope[int] {
+		return BufferTChannel(done, tee2, 3)
+	}, Inputs(tee2))
+	p.Start()
+
+	var envelopes []Envelope[int]
+	for e := range left {
+		envelopes = append(envelopes, e, <-right)
+	}
+	p.Wait()
+
+	byID := make(map[SpanID]Span)
+	for _, span := range recorder.spans {
+		byID[span.SpanID] = span
+	}
+	if len(byID) != len(recorder.spans) {
+		t.Fatalf("expected every span to have its own id")
+	}
+	for i := 0; i < len(envelopes); i += 2 {
+		leftSpan, rightSpan := byID[envelopes[i].SpanID()], byID[envelopes[i+1].SpanID()]
+		if leftSpan.Name != "left" || rightSpan.Name != "right" {
+			t.Fatalf("expected left and right spans, got %s and %s", leftSpan.Name, rightSpan.Name)
+		}
+		leftTee, rightTee := byID[leftSpan.ParentSpanID], byID[rightSpan.ParentSpanID]
+		if leftTee.Name != "tee" || rightTee.Name != "tee" || leftTee.SpanID == rightTee.SpanID {
+			t.Fatalf("expected each branch to have its own tee span, got %+v and %+v", leftTee, rightTee)
+		}
+		if leftTee.ParentSpanID != rightTee.ParentSpanID || byID[leftTee.ParentSpanID].Name != "source" {
+			t.Fatalf("expected both tee spans to come from the same source span, got %v and %v",
+				leftTee.ParentSpanID, rightTee.ParentSpanID)
+		}
+		if envelopes[i].TraceID() != envelopes[i+1].TraceID() {
+			t.Fatalf("expected both copies in one trace")
+		}
+	}
+}
+
+func TestFileSpanExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	exporter, err := NewFileSpanExporter(path)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	p := NewPipeline("file", WithSpanExporter(exporter))
+	out := AddStage(p, "source", func(done <-chan interface{}) <-chan Envelope[int] {
+		return EnvelopeChannel(done, RangeChannel(done, 0, 3, 1))
+	})
+	p.Start()
+	values, _ := Collect(p.Done(), UnwrapChannel(p.Done(), out))
+	p.Wait()
+	if err := exporter.Close(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !IntArrayEquals(values, []int{0, 1, 2}) {
+		t.Fatalf("expected [0 1 2], \n got %v", values)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer f.Close()
+	lines := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines++
+		var data otlpTracesData
+		if err := json.Unmarshal(scanner.Bytes(), &data); err != nil {
+			t.Fatalf("bad json %v: %s", err, scanner.Text())
+		}
+		span := data.ResourceSpans[0].ScopeSpans[0].Spans[0]
+		if len(span.TraceID) != 32 || len(span.SpanID) != 16 || span.Name != "source" || span.ParentSpanID != "" {
+			t.Fatalf("unexpected span %s", scanner.Text())
+		}
+	}
+	if lines != 3 {
+		t.Fatalf("expected 3 spans, got %d", lines)
+	}
+}
@@ -0,0 +1,164 @@
+package utils_generics
+
+import (
+	"sync"
+)
+
+// UnboundedChannel is a channel whose Send never blocks, for producers that
+// must not wait, such as callbacks from C libraries or signal handlers.
+// Values are queued in a ring buffer that grows as needed and shrinks again
+// as it is drained, and come out of Out in the order they were sent.
+//
+//	events := NewUnboundedChannel[Event](done)
+//	C.register_callback(func(e Event) { events.Send(e) })
+//	for e := range events.Out() {
+//		...
+//	}
+//
+// The memory it uses is only bounded by how far the reader falls behind,
+// BufferChannel is the better choice if the producer can afford to wait.
+type UnboundedChannel[T any] struct {
+	out   chan T
+	ready chan struct{} // a value has been queued, or Close was called
+
+	mu       sync.Mutex
+	buf      []T
+	head     int
+	count    int
+	inFlight bool // a value has been taken from buf and not yet received from Out
+	closed   bool
+}
+
+// minUnboundedBuffer is the smallest the ring buffer shrinks to.
+const minUnboundedBuffer = 16
+
+// NewUnboundedChannel returns an empty UnboundedChannel.  Out is closed once
+// Close has been called and every value received, or as soon as done is
+// closed, dropping any values still queued.
+func NewUnboundedChannel[T any, D any](done <-chan D) *UnboundedChannel[T] {
+	u := &UnboundedChannel[T]{
+		out:   make(chan T),
+		ready: make(chan struct{}, 1),
+		buf:   make([]T, minUnboundedBuffer),
+	}
+	go runUnbounded(u, done)
+	return u
+}
+
+// Send queues v without blocking.  It returns false, dropping v, once the
+// channel has been closed, or done has been closed.
+func (u *UnboundedChannel[T]) Send(v T) bool {
+	u.mu.Lock()
+	if u.closed {
+		u.mu.Unlock()
+		return false
+	}
+	if u.count == len(u.buf) {
+		u.resize(2 * len(u.buf))
+	}
+	u.buf[(u.head+u.count)%len(u.buf)] = v
+	u.count++
+	u.mu.Unlock()
+	u.signal()
+	return true
+}
+
+// Out returns the channel the values come out of.
+func (u *UnboundedChannel[T]) Out() <-chan T {
+	return u.out
+}
+
+// Len returns how many values have been sent and not yet received from Out.
+func (u *UnboundedChannel[T]) Len() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.inFlight {
+		return u.count + 1
+	}
+	return u.count
+}
+
+// Close stops any more values being sent.  Out is closed once the values
+// already queued have been received.
+func (u *UnboundedChannel[T]) Close() {
+	u.mu.Lock()
+	u.closed = true
+	u.mu.Unlock()
+	u.signal()
+}
+
+func (u *UnboundedChannel[T]) signal() {
+	select {
+	case u.ready <- struct{}{}:
+	default:
+	}
+}
+
+// resize copies the queued values into a buffer of size n, which must hold them.
+func (u *UnboundedChannel[T]) resize(n int) {
+	buf := make([]T, n)
+	for i := 0; i < u.count; i++ {
+		buf[i] = u.buf[(u.head+i)%len(u.buf)]
+	}
+	u.buf = buf
+	u.head = 0
+}
+
+// next takes the value at the head of the queue, shrinking the buffer once
+// it is a quarter full.  It returns false if the queue is empty.
+func (u *UnboundedChannel[T]) next() (T, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	var v T
+	if u.count == 0 {
+		return v, false
+	}
+	v = u.buf[u.head]
+	var zero T
+	u.buf[u.head] = zero // let the garbage collector have it
+	u.head = (u.head + 1) % len(u.buf)
+	u.count--
+	u.inFlight = true
+	if len(u.buf) > minUnboundedBuffer && u.count <= len(u.buf)/4 {
+		u.resize(len(u.buf) / 2)
+	}
+	return v, true
+}
+
+// runUnbounded passes the queued values to Out until done is closed, or the
+// channel is closed and drained.
+func runUnbounded[T any, D any](u *UnboundedChannel[T], done <-chan D) {
+	defer close(u.out)
+	defer func() {
+		u.mu.Lock()
+		defer u.mu.Unlock()
+		u.closed = true
+		u.buf, u.head, u.count, u.inFlight = nil, 0, 0, false
+	}()
+	for {
+		v, ok := u.next()
+		if ok == false {
+			u.mu.Lock()
+			closed := u.closed
+			u.mu.Unlock()
+			if closed {
+				return
+			}
+			select {
+			case <-done:
+				return
+			case <-u.ready:
+			}
+			continue
+		}
+
+		select {
+		case <-done:
+			return
+		case u.out <- v:
+			u.mu.Lock()
+			u.inFlight = false
+			u.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package utils_generics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUnboundedChannel(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	u := NewUnboundedChannel[int](done)
+	// nobody is reading, and Send does not block.
+	for i := 0; i < 1000; i++ {
+		if u.Send(i) == false {
+			t.Fatalf("expected Send to work")
+		}
+	}
+	if u.Len() != 1000 {
+		t.Fatalf("expected 1000, got %d", u.Len())
+	}
+
+	for i := 0; i < 1000; i++ {
+		if v := <-u.Out(); v != i {
+			t.Fatalf("expected %d, got %d", i, v)
+		}
+	}
+	u.mu.Lock()
+	size := len(u.buf)
+	u.mu.Unlock()
+	if size != minUnboundedBuffer {
+		t.Fatalf("expected the buffer to shrink back to %d, got %d", minUnboundedBuffer, size)
+	}
+
+	u.Send(1000)
+	u.Close()
+	if u.Send(1001) {
+		t.Fatalf("expected Send to fail once closed")
+	}
+	result, _ := Collect(done, u.Out())
+	if !IntArrayEquals(result, []int{1000}) {
+		t.Fatalf("expected [1000], \n got %v", result)
+	}
+}
+
+func TestUnboundedChannelDone(t *testing.T) {
+	done := make(chan interface{})
+	u := NewUnboundedChannel[string](done)
+	u.Send("dropped")
+	close(done)
+
+	select {
+	case _, ok := <-u.Out():
+		// the value may have been on its way out already.
+		if ok {
+			if _, ok = <-u.Out(); ok {
+				t.Fatalf("expected Out to close")
+			}
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected Out to close")
+	}
+	if u.Send("too late") || u.Len() != 0 {
+		t.Fatalf("expected Send to fail and nothing queued")
+	}
+}
+
+func TestUnboundedChannelConcurrentSenders(t *testing.T) {
+	done := make(chan interface{})
+	defer close(done)
+
+	u := NewUnboundedChannel[int](done)
+	for s := 0; s < 4; s++ {
+		go func() {
+			for i := 0; i < 250; i++ {
+				u.Send(i)
+			}
+		}()
+	}
+	count := 0
+	for range TakeTChannel(done, u.Out(), 1000) {
+		count++
+	}
+	if count != 1000 {
+		t.Fatalf("expected 1000, got %d", count)
+	}
+}
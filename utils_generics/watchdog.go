@@ -0,0 +1,170 @@
+package utils_generics
+
+import (
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// A watchdog for pipelines that hang.
+//
+// Each goroutine passing along a stage's output keeps track of what it is
+// blocked on and since when: receiving means it is waiting on the stage to
+// produce, sending means it is waiting on the next stage to take.  A
+// Pipeline created WithWatchdog checks them while it runs and reports any
+// that have been blocked for longer than the threshold, along with the stacks
+// of every goroutine, which is usually enough to spot the deadlock.
+//
+//	p := NewPipeline("orders", WithWatchdog(WatchdogConfig{
+//		Threshold: time.Minute,
+//		OnStall: func(s Stall) {
+//			log.Printf("%s/%s blocked on %s for %v\n%s", s.Pipeline, s.Stage, s.Op, s.Blocked, s.Stacks)
+//		},
+//	}))
+//
+// Each stall is reported once, until the stage gets going again.
+
+// portState is what the goroutine passing along a stage's output is doing.
+type portState int32
+
+const (
+	portIdle portState = iota
+	portReceiving
+	portSending
+)
+
+// stagePort is one output of a stage.
+type stagePort struct {
+	buffer  func() (int, int) // len and cap of the stage's output, once started
+	metrics *stageMetrics
+	state   atomic.Int32
+	since   atomic.Int64 // UnixNano the state last changed
+}
+
+// blocked records what the port is about to wait on, or portIdle when it is done waiting.
+func (port *stagePort) blocked(state portState) {
+	port.since.Store(time.Now().UnixNano())
+	port.state.Store(int32(state))
+}
+
+// Stall describes a stage that has been blocked for too long.
+type Stall struct {
+	Pipeline   string
+	PipelineID uint64
+	Stage      string
+	Op         string // "receive", waiting on the stage, or "send", waiting on the next stage
+	Blocked    time.Duration
+	Stacks     []byte // stacks of every goroutine, as from runtime.Stack
+}
+
+// WatchdogConfig configures WithWatchdog.
+type WatchdogConfig struct {
+	// Threshold is how long a stage may be blocked before it is reported.
+	Threshold time.Duration
+	// Interval is how often to check, Threshold/2 if it is 0.
+	Interval time.Duration
+	// OnStall is called from the watchdog's goroutine for each stall.
+	OnStall func(Stall)
+	// Cancel stops the Pipeline once a stall has been reported.
+	Cancel bool
+}
+
+// WithWatchdog watches the Pipeline for stalls from Start until every stage has finished.
+func WithWatchdog(config WatchdogConfig) PipelineOption {
+	return func(p *Pipeline) {
+		p.watchdog = &config
+	}
+}
+
+// watch runs the watchdog until the Pipeline has finished.
+func (p *Pipeline) watch() {
+	config := p.watchdog
+	interval := config.Interval
+	if interval <= 0 {
+		interval = config.Threshold / 2
+	}
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	reported := make(map[*stagePort]int64) // the since of the stall last reported
+	for {
+		select {
+		case <-p.finished:
+			return
+		case <-ticker.C:
+		}
+		if p.stopping() {
+			continue
+		}
+
+		for _, stall := range p.stalls(config.Threshold, reported) {
+			if config.OnStall != nil {
+				config.OnStall(stall)
+			}
+			if config.Cancel {
+				p.Stop()
+			}
+		}
+	}
+}
+
+// stalls returns the ports blocked for longer than threshold that have not
+// been reported yet.
+func (p *Pipeline) stalls(threshold time.Duration, reported map[*stagePort]int64) []Stall {
+	p.mu.Lock()
+	stages := append([]*stage(nil), p.stages...)
+	p.mu.Unlock()
+
+	now := time.Now()
+	var stalls []Stall
+	var stacks []byte
+	for _, s := range stages {
+		p.mu.Lock()
+		ports := append([]*stagePort(nil), s.ports...)
+		p.mu.Unlock()
+
+		for _, port := range ports {
+			since := port.since.Load()
+			op := "receive"
+			switch portState(port.state.Load()) {
+			case portIdle:
+				continue
+			case portSending:
+				op = "send"
+			}
+			blocked := now.Sub(time.Unix(0, since))
+			if blocked < threshold || reported[port] == since {
+				continue
+			}
+			reported[port] = since
+
+			if stacks == nil {
+				stacks = allStacks()
+			}
+			stalls = append(stalls, Stall{
+				Pipeline:   p.name,
+				PipelineID: p.id,
+				Stage:      s.name,
+				Op:         op,
+				Blocked:    blocked,
+				Stacks:     stacks,
+			})
+		}
+	}
+	return stalls
+}
+
+// allStacks returns the stacks of every goroutine.
+func allStacks() []byte {
+	buf := make([]byte, 64*1024)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
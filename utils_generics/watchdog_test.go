@@ -0,0 +1,66 @@
+package utils_generics
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestWatchdogReportsStall(t *testing.T) {
+	stalls := make(chan Stall, 10)
+	p := NewPipeline("stuck", WithWatchdog(WatchdogConfig{
+		Threshold: 50 * time.Millisecond,
+		Interval:  10 * time.Millisecond,
+		OnStall:   func(s Stall) { stalls <- s },
+		Cancel:    true,
+	}))
+	// nobody ever reads from repeat, so the stage is stuck sending.
+	AddStage(p, "repeat", func(done <-chan interface{}) <-chan interface{} {
+		return RepeatValueChannel(done, 1)
+	})
+	p.Start()
+
+	select {
+	case s := <-stalls:
+		fmt.Printf("%s/%s blocked on %s for %v\n", s.Pipeline, s.Stage, s.Op, s.Blocked)
+		if s.Pipeline != "stuck" || s.PipelineID != p.ID() || s.Stage != "repeat" || s.Op != "send" {
+			t.Fatalf("expected stuck/repeat blocked on send, got %+v", s)
+		}
+		if s.Blocked < 50*time.Millisecond {
+			t.Fatalf("expected blocked for at least 50ms, got %v", s.Blocked)
+		}
+		if !bytes.Contains(s.Stacks, []byte("goroutine ")) {
+			t.Fatalf("expected goroutine stacks, got %s", s.Stacks)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected a stall to be reported")
+	}
+
+	// Cancel stops the pipeline.
+	p.Wait()
+	if len(stalls) != 0 {
+		t.Fatalf("expected the stall to be reported once, got %d more", len(stalls))
+	}
+}
+
+func TestWatchdogQuiet(t *testing.T) {
+	stalls := 0
+	p := NewPipeline("busy", WithWatchdog(WatchdogConfig{
+		Threshold: time.Second,
+		Interval:  10 * time.Millisecond,
+		OnStall:   func(s Stall) { stalls++ },
+	}))
+	out := AddStage(p, "generator", func(done <-chan interface{}) <-chan int {
+		return RangeChannel(done, 0, 10, 1)
+	})
+	p.Start()
+	for range out {
+		time.Sleep(5 * time.Millisecond)
+	}
+	p.Wait()
+
+	if stalls != 0 {
+		t.Fatalf("expected no stalls, got %d", stalls)
+	}
+}